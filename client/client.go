@@ -2,31 +2,81 @@ package client
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest"
 	"github.com/ehotinger/solstice/iam"
+	"github.com/ehotinger/solstice/pkg/httplog"
 )
 
+// trace receives a redacted trace of every HTTP request when it's not nil.
+var trace io.Writer
+
+// EnableTracing writes a trace of every HTTP request made by the clients
+// created afterwards to w. Credentials and secrets are redacted.
+func EnableTracing(w io.Writer) {
+	trace = w
+}
+
+// baseURI returns the Resource Manager endpoint of the selected cloud.
+func baseURI() string {
+	return strings.TrimSuffix(iam.Environment().ResourceManagerEndpoint, "/")
+}
+
+// configure sets up the authorization, user agent and tracing of a client for a subscription.
+func configure(c *autorest.Client, subID string) error {
+	return configureForTenant(c, iam.SubscriptionTenant(subID))
+}
+
+// configureForTenant sets up the authorization, user agent and tracing of a
+// client for the resources of a tenant.
+func configureForTenant(c *autorest.Client, tenant string) error {
+	auth, err := iam.GetResourceManagementAuthorizerForTenant(iam.AuthGrantType(), tenant)
+	if err != nil {
+		return fmt.Errorf("Failed to get client. Err: %v", err)
+	}
+	c.Authorizer = auth
+	c.AddToUserAgent(containerregistry.UserAgent())
+	if trace != nil {
+		c.Sender = httplog.NewSender(c.Sender, trace)
+	}
+	return nil
+}
+
 // GetRegistriesClient returns a client to interact with registry resources.
 func GetRegistriesClient(subID string) (c containerregistry.RegistriesClient, err error) {
-	registriesClient := containerregistry.NewRegistriesClient(subID)
-	auth, err := iam.GetResourceManagementAuthorizer(iam.AuthGrantType())
-	if err != nil {
-		return c, fmt.Errorf("Failed to get client. Err: %v", err)
+	registriesClient := containerregistry.NewRegistriesClientWithBaseURI(baseURI(), subID)
+	if err = configure(&registriesClient.Client, subID); err != nil {
+		return c, err
 	}
-	registriesClient.Authorizer = auth
-	registriesClient.AddToUserAgent(containerregistry.UserAgent())
 	return registriesClient, nil
 }
 
 // GetBuildsClient returns a client to interact with builds.
 func GetBuildsClient(subID string) (c containerregistry.BuildsClient, err error) {
-	buildsClient := containerregistry.NewBuildsClient(subID)
-	auth, err := iam.GetResourceManagementAuthorizer(iam.AuthGrantType())
-	if err != nil {
-		return c, fmt.Errorf("Failed to get client. Err: %v", err)
+	buildsClient := containerregistry.NewBuildsClientWithBaseURI(baseURI(), subID)
+	if err = configure(&buildsClient.Client, subID); err != nil {
+		return c, err
 	}
-	buildsClient.Authorizer = auth
-	buildsClient.AddToUserAgent(containerregistry.UserAgent())
 	return buildsClient, nil
 }
+
+// GetBuildTasksClient returns a client to interact with build tasks.
+func GetBuildTasksClient(subID string) (c containerregistry.BuildTasksClient, err error) {
+	buildTasksClient := containerregistry.NewBuildTasksClientWithBaseURI(baseURI(), subID)
+	if err = configure(&buildTasksClient.Client, subID); err != nil {
+		return c, err
+	}
+	return buildTasksClient, nil
+}
+
+// GetBuildStepsClient returns a client to interact with the steps of build tasks.
+func GetBuildStepsClient(subID string) (c containerregistry.BuildStepsClient, err error) {
+	buildStepsClient := containerregistry.NewBuildStepsClientWithBaseURI(baseURI(), subID)
+	if err = configure(&buildStepsClient.Client, subID); err != nil {
+		return c, err
+	}
+	return buildStepsClient, nil
+}
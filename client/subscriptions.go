@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// subscriptionsAPIVersion is the Resource Manager API version used to list subscriptions.
+const subscriptionsAPIVersion = "2016-06-01"
+
+// Subscription is a subscription the signed in identity can access.
+type Subscription struct {
+	ID    string `json:"subscriptionId"`
+	Name  string `json:"displayName"`
+	State string `json:"state"`
+}
+
+type subscriptionListResult struct {
+	Value    []Subscription `json:"value"`
+	NextLink string         `json:"nextLink"`
+}
+
+// ListSubscriptions lists the subscriptions of the given tenant which the
+// signed in identity can access. The identity's own tenant is used if the
+// tenant is empty.
+func ListSubscriptions(ctx context.Context, tenant string) ([]Subscription, error) {
+	c := autorest.NewClientWithUserAgent("")
+	if err := configureForTenant(&c, tenant); err != nil {
+		return nil, err
+	}
+
+	preparer := autorest.CreatePreparer(
+		autorest.AsGet(),
+		autorest.WithBaseURL(baseURI()),
+		autorest.WithPath("/subscriptions"),
+		autorest.WithQueryParameters(map[string]interface{}{
+			"api-version": subscriptionsAPIVersion,
+		}))
+	req, err := preparer.Prepare((&http.Request{}).WithContext(ctx))
+
+	var subs []Subscription
+	for err == nil && req != nil {
+		var resp *http.Response
+		resp, err = autorest.SendWithSender(c, req, autorest.DoRetryForStatusCodes(c.RetryAttempts, c.RetryDuration, autorest.StatusCodesForRetry...))
+		if err != nil {
+			return nil, autorest.NewErrorWithError(err, "client", "ListSubscriptions", resp, "Failure sending request")
+		}
+		var result subscriptionListResult
+		err = autorest.Respond(
+			resp,
+			c.ByInspecting(),
+			azure.WithErrorUnlessStatusCode(http.StatusOK),
+			autorest.ByUnmarshallingJSON(&result),
+			autorest.ByClosing())
+		if err != nil {
+			return nil, autorest.NewErrorWithError(err, "client", "ListSubscriptions", resp, "Failure responding to request")
+		}
+		subs = append(subs, result.Value...)
+
+		req = nil
+		if result.NextLink != "" {
+			req, err = autorest.Prepare((&http.Request{}).WithContext(ctx),
+				autorest.AsGet(),
+				autorest.WithBaseURL(result.NextLink))
+		}
+	}
+	if err != nil {
+		return nil, autorest.NewErrorWithError(err, "client", "ListSubscriptions", nil, "Failure preparing request")
+	}
+	return subs, nil
+}
@@ -0,0 +1,625 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/manifest"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const applyLongMessage = `
+Converge the build tasks and steps of a registry to a manifest.
+
+The manifest is a YAML or JSON file listing build tasks and their steps. It's
+compared with the registry, and the resulting plan of creations, updates and
+deletions is shown before it's carried out. Optional fields which are left out
+of the manifest aren't managed: they get the service's defaults when a task or
+step is created, and are left alone otherwise.
+
+Build tasks and steps which aren't part of the manifest are only deleted with
+--prune. Changing the location or source repository of a task replaces it,
+which deletes and recreates its steps as well. Changing the base images of a
+step replaces the step.
+
+Secrets are never part of the manifest. Source control tokens and secret build
+arguments are read from the files or environment variables the manifest names,
+and only when they're needed. Relative file paths are resolved against the
+directory of the manifest. The values of secret build arguments can't be
+compared, use 'solstice step args set' to change them.
+
+Example manifest:
+
+  apiVersion: solstice/v1
+  buildTasks:
+  - name: mytask
+    location: westus
+    source:
+      type: Github
+      repositoryUrl: https://github.com/org/repo
+      tokenEnv: GITHUB_TOKEN
+    steps:
+    - name: mystep
+      image: myapp:latest
+      baseImages:
+      - BuildTime=node:8
+      buildArgs:
+      - name: VERSION
+        value: "1.0"
+      - name: NPM_TOKEN
+        secret: true
+        valueFile: secrets/npm-token
+
+Examples:
+  solstice apply --rg mygroup --n myregistry -f tasks.yaml --dry-run
+  solstice apply --rg mygroup --n myregistry -f tasks.yaml --prune --yes
+`
+
+type applyCmd struct {
+	resourceGroupName string
+	registryName      string
+	file              string
+	prune             bool
+	dryRun            bool
+	yes               bool
+	// createOnly is only set by 'task import'.
+	createOnly bool
+	in         io.Reader
+	out        io.Writer
+}
+
+func newApplyCmd(out io.Writer) *cobra.Command {
+	applyCmd := &applyCmd{
+		in:  os.Stdin,
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Converge build tasks and steps to a manifest",
+		Long:  applyLongMessage,
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return applyCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&applyCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&applyCmd.registryName, "n", "", "The name of the registry")
+	f.StringVarP(&applyCmd.file, "file", "f", "", "The manifest file, or '-' to read it from stdin")
+	f.BoolVar(&applyCmd.prune, "prune", false, "Delete build tasks and steps which aren't part of the manifest")
+	f.BoolVar(&applyCmd.dryRun, "dry-run", false, "Only show the plan, without making any changes")
+	f.BoolVarP(&applyCmd.yes, "yes", "y", false, "Don't ask for confirmation")
+	markStdinFlags(f, "file")
+
+	return cmd
+}
+
+func (c *applyCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	m, err := c.readManifest()
+	if err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*30)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	state, err := fetchRegistryState(ctx, tc, sc, c.resourceGroupName, c.registryName, m)
+	if err != nil {
+		return err
+	}
+
+	pl := &planner{prune: c.prune, createOnly: c.createOnly}
+	if c.file != "-" {
+		pl.dir = filepath.Dir(c.file)
+	}
+	changes, err := pl.plan(m, state)
+	if err != nil {
+		return validationError(err)
+	}
+
+	plan := view.Plan{Items: []view.PlanAction{}}
+	for _, change := range changes {
+		plan.Items = append(plan.Items, change.PlanAction)
+	}
+	err = p.Print(c.out, plan, func(w io.Writer) error {
+		return printPlanTable(w, plan.Items)
+	})
+	if err != nil || c.dryRun || len(changes) == 0 {
+		return err
+	}
+
+	// Read every secret before making the first change, so that a missing one
+	// doesn't leave the registry half converged.
+	a := &applier{
+		tc:                tc,
+		sc:                sc,
+		resourceGroupName: c.resourceGroupName,
+		registryName:      c.registryName,
+		planner:           pl,
+	}
+	if err = a.resolveSecrets(changes); err != nil {
+		return validationError(err)
+	}
+
+	if !c.yes {
+		// The manifest may have been read from stdin, which can't be asked as well.
+		if c.file == "-" {
+			return validationError(errors.New("the manifest was read from stdin, so the plan can't be confirmed; use --yes to apply it"))
+		}
+		ok, err := confirm(c.in, os.Stderr, "Apply the plan?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Aborted.")
+			return nil
+		}
+	}
+
+	for _, change := range changes {
+		if err = a.apply(ctx, change); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Applied %d change(s).\n", len(changes))
+	return nil
+}
+
+// readManifest validates the flags and reads the manifest.
+func (c *applyCmd) readManifest() (*manifest.Manifest, error) {
+	if err := validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return nil, err
+	}
+	if c.file == "" {
+		return nil, errors.New("a manifest must be specified with --file")
+	}
+
+	if c.file == "-" {
+		return manifest.Read(c.in)
+	}
+	f, err := os.Open(c.file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the manifest: %v", err)
+	}
+	defer f.Close()
+	return manifest.Read(f)
+}
+
+// applier makes the changes of a plan.
+type applier struct {
+	tc                containerregistry.BuildTasksClient
+	sc                containerregistry.BuildStepsClient
+	resourceGroupName string
+	registryName      string
+	planner           *planner
+	// tokens holds the source control tokens of the build tasks to create, by task name.
+	tokens map[string]string
+	// args holds the build arguments of the steps to create or update, by task and step name.
+	args map[[2]string][]containerregistry.BuildArgument
+}
+
+// resolveSecrets reads the tokens and build arguments the changes need.
+func (a *applier) resolveSecrets(changes []plannedChange) error {
+	a.tokens = make(map[string]string)
+	a.args = make(map[[2]string][]containerregistry.BuildArgument)
+
+	for _, change := range changes {
+		switch {
+		case change.Action == planDelete:
+			continue
+		case change.Kind == kindBuildTask && change.Action != planUpdate:
+			token, err := a.readToken(change.task.Source)
+			if err != nil {
+				return fmt.Errorf("build task %s: %v", change.task.Name, err)
+			}
+			a.tokens[change.task.Name] = token
+		case change.Kind == kindBuildStep:
+			if change.Action == planUpdate && !updatesBuildArgs(change.Changes) {
+				continue
+			}
+			var args []containerregistry.BuildArgument
+			for _, arg := range change.step.BuildArgs {
+				value, err := a.planner.buildArgValue(arg)
+				if err != nil {
+					return fmt.Errorf("build task %s, step %s: %v", change.task.Name, change.step.Name, err)
+				}
+				args = append(args, newBuildArg(arg.Name, value, arg.Secret))
+			}
+			a.args[[2]string{change.task.Name, change.step.Name}] = args
+		}
+	}
+	return nil
+}
+
+func (a *applier) readToken(source manifest.Source) (string, error) {
+	if source.TokenEnv != "" {
+		return readSecretEnv(source.TokenEnv)
+	}
+	path, err := a.planner.path(source.TokenFile)
+	if err != nil {
+		return "", err
+	}
+	return readSecretFile(path, nil)
+}
+
+// apply makes a single change.
+func (a *applier) apply(ctx context.Context, change plannedChange) error {
+	switch change.Kind {
+	case kindBuildTask:
+		switch change.Action {
+		case planCreate:
+			return a.createTask(ctx, change.task)
+		case planUpdate:
+			return a.updateTask(ctx, change.task, change.current)
+		case planReplace:
+			if err := a.deleteTask(ctx, change.BuildTask); err != nil {
+				return err
+			}
+			return a.createTask(ctx, change.task)
+		case planDelete:
+			return a.deleteTask(ctx, change.BuildTask)
+		}
+	case kindBuildStep:
+		switch change.Action {
+		case planCreate:
+			return a.createStep(ctx, change.task, change.step)
+		case planUpdate:
+			return a.updateStep(ctx, change.task, change.step, updatesBuildArgs(change.Changes))
+		case planReplace:
+			if err := a.deleteStep(ctx, change.BuildTask, change.Step); err != nil {
+				return err
+			}
+			return a.createStep(ctx, change.task, change.step)
+		case planDelete:
+			return a.deleteStep(ctx, change.BuildTask, change.Step)
+		}
+	}
+	return fmt.Errorf("unknown change: %s %s", change.Action, change.Kind)
+}
+
+func (a *applier) createTask(ctx context.Context, t *manifest.BuildTask) error {
+	task, err := newTaskFromManifest(t, a.tokens[t.Name])
+	if err != nil {
+		return validationError(fmt.Errorf("build task %s: %v", t.Name, err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Creating build task %s...\n", t.Name)
+	future, err := a.tc.Create(ctx, a.resourceGroupName, a.registryName, t.Name, task)
+	if err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while creating build task %s", t.Name), err)
+	}
+	if err = future.WaitForCompletion(ctx, a.tc.Client); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while waiting for build task %s to be created", t.Name), err)
+	}
+	if _, err = future.Result(a.tc); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while creating build task %s", t.Name), err)
+	}
+	return nil
+}
+
+func (a *applier) updateTask(ctx context.Context, t *manifest.BuildTask, current *containerregistry.BuildTask) error {
+	params, err := taskUpdateFromManifest(t, current)
+	if err != nil {
+		return validationError(fmt.Errorf("build task %s: %v", t.Name, err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Updating build task %s...\n", t.Name)
+	future, err := a.tc.Update(ctx, a.resourceGroupName, a.registryName, t.Name, params)
+	if err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while updating build task %s", t.Name), err)
+	}
+	if err = future.WaitForCompletion(ctx, a.tc.Client); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while waiting for build task %s to be updated", t.Name), err)
+	}
+	if _, err = future.Result(a.tc); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while updating build task %s", t.Name), err)
+	}
+	return nil
+}
+
+func (a *applier) deleteTask(ctx context.Context, name string) error {
+	fmt.Fprintf(os.Stderr, "Deleting build task %s...\n", name)
+	future, err := a.tc.Delete(ctx, a.resourceGroupName, a.registryName, name)
+	if err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while deleting build task %s", name), err)
+	}
+	if err = future.WaitForCompletion(ctx, a.tc.Client); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while waiting for build task %s to be deleted", name), err)
+	}
+	if _, err = future.Result(a.tc); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while deleting build task %s", name), err)
+	}
+	return nil
+}
+
+func (a *applier) createStep(ctx context.Context, t *manifest.BuildTask, s *manifest.Step) error {
+	step, err := newStepFromManifest(s, a.args[[2]string{t.Name, s.Name}])
+	if err != nil {
+		return validationError(fmt.Errorf("build task %s, step %s: %v", t.Name, s.Name, err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Creating build step %s of build task %s...\n", s.Name, t.Name)
+	future, err := a.sc.Create(ctx, a.resourceGroupName, a.registryName, t.Name, s.Name, step)
+	if err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while creating build step %s", s.Name), err)
+	}
+	if err = future.WaitForCompletion(ctx, a.sc.Client); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while waiting for build step %s to be created", s.Name), err)
+	}
+	if _, err = future.Result(a.sc); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while creating build step %s", s.Name), err)
+	}
+	return nil
+}
+
+func (a *applier) updateStep(ctx context.Context, t *manifest.BuildTask, s *manifest.Step, withArgs bool) error {
+	var args *[]containerregistry.BuildArgument
+	if withArgs {
+		// An empty list, rather than null, clears the step's build arguments.
+		resolved := append([]containerregistry.BuildArgument{}, a.args[[2]string{t.Name, s.Name}]...)
+		args = &resolved
+	}
+	params, err := stepUpdateFromManifest(s, args)
+	if err != nil {
+		return validationError(fmt.Errorf("build task %s, step %s: %v", t.Name, s.Name, err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Updating build step %s of build task %s...\n", s.Name, t.Name)
+	future, err := a.sc.Update(ctx, a.resourceGroupName, a.registryName, t.Name, s.Name, params)
+	if err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while updating build step %s", s.Name), err)
+	}
+	if err = future.WaitForCompletion(ctx, a.sc.Client); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while waiting for build step %s to be updated", s.Name), err)
+	}
+	if _, err = future.Result(a.sc); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while updating build step %s", s.Name), err)
+	}
+	return nil
+}
+
+func (a *applier) deleteStep(ctx context.Context, task, name string) error {
+	fmt.Fprintf(os.Stderr, "Deleting build step %s of build task %s...\n", name, task)
+	future, err := a.sc.Delete(ctx, a.resourceGroupName, a.registryName, task, name)
+	if err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while deleting build step %s", name), err)
+	}
+	if err = future.WaitForCompletion(ctx, a.sc.Client); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while waiting for build step %s to be deleted", name), err)
+	}
+	if _, err = future.Result(a.sc); err != nil {
+		return wrapAPIError(fmt.Sprintf("Errored while deleting build step %s", name), err)
+	}
+	return nil
+}
+
+// newTaskFromManifest maps a manifest entry onto a BuildTask, using the same defaults as 'task create'.
+func newTaskFromManifest(t *manifest.BuildTask, token string) (containerregistry.BuildTask, error) {
+	var task containerregistry.BuildTask
+
+	alias := t.Alias
+	if alias == "" {
+		alias = t.Name
+	}
+	status := containerregistry.Enabled
+	if t.Status != "" {
+		var err error
+		if status, err = parseTaskStatus(t.Status); err != nil {
+			return task, err
+		}
+	}
+	platform := &manifest.Platform{OS: "linux"}
+	if t.Platform != nil {
+		platform = t.Platform
+	}
+	platformProps, err := manifestPlatform(platform)
+	if err != nil {
+		return task, err
+	}
+	timeout := int32(defaultTaskTimeout)
+	if t.Timeout != nil {
+		timeout = *t.Timeout
+	}
+	if err = validateTimeout(timeout); err != nil {
+		return task, err
+	}
+	sourceControlType, err := parseSourceControlType(t.Source.Type)
+	if err != nil {
+		return task, err
+	}
+	tokenType := containerregistry.PAT
+	if t.Source.TokenType != "" {
+		if tokenType, err = parseTokenType(t.Source.TokenType); err != nil {
+			return task, err
+		}
+	}
+
+	task = containerregistry.BuildTask{
+		Tags: manifestTags(t.Tags),
+		BuildTaskProperties: &containerregistry.BuildTaskProperties{
+			Alias:  to.StringPtr(alias),
+			Status: status,
+			SourceRepository: &containerregistry.SourceRepositoryProperties{
+				SourceControlType:      sourceControlType,
+				RepositoryURL:          to.StringPtr(t.Source.RepositoryURL),
+				IsCommitTriggerEnabled: to.BoolPtr(t.Source.IsCommitTriggerEnabled()),
+				SourceControlAuthProperties: &containerregistry.SourceControlAuthInfo{
+					TokenType: tokenType,
+					Token:     to.StringPtr(token),
+				},
+			},
+			Platform: platformProps,
+			Timeout:  to.Int32Ptr(timeout),
+		},
+		Location: to.StringPtr(t.Location),
+	}
+	return task, nil
+}
+
+// taskUpdateFromManifest maps the managed fields of a manifest entry onto
+// BuildTaskUpdateParameters. The platform is replaced as a whole, so an
+// unmanaged cpu count is carried over from the current task.
+func taskUpdateFromManifest(t *manifest.BuildTask, current *containerregistry.BuildTask) (containerregistry.BuildTaskUpdateParameters, error) {
+	props := &containerregistry.BuildTaskPropertiesUpdateParameters{}
+	params := containerregistry.BuildTaskUpdateParameters{
+		BuildTaskPropertiesUpdateParameters: props,
+		Tags:                                manifestTags(t.Tags),
+	}
+	if current != nil {
+		params.Tags = keepTokenRotatedTag(params.Tags, current.Tags)
+	}
+
+	if t.Alias != "" {
+		props.Alias = to.StringPtr(t.Alias)
+	}
+	if t.Status != "" {
+		status, err := parseTaskStatus(t.Status)
+		if err != nil {
+			return params, err
+		}
+		props.Status = status
+	}
+	if t.Platform != nil {
+		platform, err := manifestPlatform(t.Platform)
+		if err != nil {
+			return params, err
+		}
+		if platform.CPU == nil && current != nil && current.BuildTaskProperties != nil && current.Platform != nil {
+			platform.CPU = current.Platform.CPU
+		}
+		props.Platform = platform
+	}
+	if t.Timeout != nil {
+		if err := validateTimeout(*t.Timeout); err != nil {
+			return params, err
+		}
+		props.Timeout = to.Int32Ptr(*t.Timeout)
+	}
+	if t.Source.CommitTrigger != nil {
+		props.SourceRepository = &containerregistry.SourceRepositoryUpdateParameters{
+			IsCommitTriggerEnabled: to.BoolPtr(*t.Source.CommitTrigger),
+		}
+	}
+	return params, nil
+}
+
+// newStepFromManifest maps a manifest entry onto a Docker BuildStep, using the same defaults as 'step create'.
+func newStepFromManifest(s *manifest.Step, args []containerregistry.BuildArgument) (containerregistry.BuildStep, error) {
+	var step containerregistry.BuildStep
+
+	props := containerregistry.DockerBuildStep{
+		Branch:           to.StringPtr(orDefault(s.Branch, "master")),
+		IsPushEnabled:    to.BoolPtr(s.Push == nil || *s.Push),
+		DockerFilePath:   to.StringPtr(orDefault(s.Dockerfile, "Dockerfile")),
+		ContextPath:      to.StringPtr(orDefault(s.Context, ".")),
+		BaseImageTrigger: containerregistry.Runtime,
+	}
+	if s.Image != "" {
+		if err := validateImageName(s.Image); err != nil {
+			return step, err
+		}
+		props.ImageName = to.StringPtr(s.Image)
+	}
+	if s.BaseImageTrigger != "" {
+		trigger, err := parseBaseImageTrigger(s.BaseImageTrigger)
+		if err != nil {
+			return step, err
+		}
+		props.BaseImageTrigger = trigger
+	}
+	dependencies, err := parseBaseImageDependencies(s.BaseImages, props.BaseImageTrigger != containerregistry.None)
+	if err != nil {
+		return step, err
+	}
+	if len(dependencies) > 0 {
+		props.BaseImageDependencies = &dependencies
+	}
+	if len(args) > 0 {
+		props.BuildArguments = &args
+	}
+
+	step.BasicBuildStepProperties = props
+	return step, nil
+}
+
+// stepUpdateFromManifest maps the managed fields of a manifest entry onto
+// BuildStepUpdateParameters. The build arguments are only replaced if args isn't nil.
+func stepUpdateFromManifest(s *manifest.Step, args *[]containerregistry.BuildArgument) (containerregistry.BuildStepUpdateParameters, error) {
+	props := containerregistry.DockerBuildStepUpdateParameters{
+		BuildArguments: args,
+	}
+	if s.Branch != "" {
+		props.Branch = to.StringPtr(s.Branch)
+	}
+	if s.Image != "" {
+		if err := validateImageName(s.Image); err != nil {
+			return containerregistry.BuildStepUpdateParameters{}, err
+		}
+		props.ImageName = to.StringPtr(s.Image)
+	}
+	if s.Push != nil {
+		props.IsPushEnabled = to.BoolPtr(*s.Push)
+	}
+	if s.Dockerfile != "" {
+		props.DockerFilePath = to.StringPtr(s.Dockerfile)
+	}
+	if s.Context != "" {
+		props.ContextPath = to.StringPtr(s.Context)
+	}
+	if s.BaseImageTrigger != "" {
+		trigger, err := parseBaseImageTrigger(s.BaseImageTrigger)
+		if err != nil {
+			return containerregistry.BuildStepUpdateParameters{}, err
+		}
+		props.BaseImageTrigger = trigger
+	}
+	return containerregistry.BuildStepUpdateParameters{BasicBuildStepPropertiesUpdateParameters: props}, nil
+}
+
+// manifestTags converts the tags of a manifest entry, keeping nil as nil so that unmanaged tags are left alone.
+func manifestTags(tags map[string]string) map[string]*string {
+	if tags == nil {
+		return nil
+	}
+	result := make(map[string]*string, len(tags))
+	for k, v := range tags {
+		result[k] = to.StringPtr(v)
+	}
+	return result
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
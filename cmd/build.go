@@ -5,18 +5,58 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
-	"github.com/Azure/go-autorest/autorest/azure/cli"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/archive"
+	"github.com/ehotinger/solstice/pkg/blob"
+	"github.com/ehotinger/solstice/pkg/dockerignore"
+	"github.com/ehotinger/solstice/pkg/fileutils"
+	"github.com/ehotinger/solstice/pkg/printer"
+	"github.com/ehotinger/solstice/pkg/view"
 	"github.com/spf13/cobra"
 )
 
+const buildLongMessage = `
+Queue a quick build using a local directory as the build context.
+
+The directory (defaults to the current directory) is packaged as a gzip
+compressed tarball, uploaded to the registry's build source storage and
+then queued for building.
+
+Files matching the patterns in the context's .dockerignore file (or the file
+given by --ignore-file) are excluded, using the same rules as 'docker build'.
+
+Unless --no-wait is used, the command waits for the build to finish and exits
+with a code reflecting its final status (see 'solstice help').
+
+Examples:
+  solstice build --rg mygroup --n myregistry -t myapp:v1 .
+  solstice build --rg mygroup --n myregistry -t myapp:v1 -f docker/Dockerfile --build-arg VERSION=1.0 .
+  solstice build --rg mygroup --n myregistry --no-push --os windows .
+`
+
 type buildCmd struct {
 	resourceGroupName string
 	registryName      string
+	contextDir        string
+	ignoreFile        string
+	imageNames        []string
+	dockerfile        string
+	buildArgs         []string
+	secretBuildArgs   []string
+	osType            string
+	cpu               int32
+	timeout           int32
+	noPush            bool
+	follow            bool
+	noWait            bool
 	out               io.Writer
 }
 
@@ -27,89 +67,309 @@ func newBuildCmd(out io.Writer) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:   "build",
+		Use:   "build [PATH]",
 		Short: "Queue a build",
-		Long:  "Queue a build",
+		Long:  buildLongMessage,
+		Args:  args(cobra.MaximumNArgs(1)),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
-			defer cancel()
-
-			subscription, err := getSubscriptionFromProfile()
-			if err != nil {
-				return fmt.Errorf("There was an error while grabbing the subscription: %v", err)
+			buildCmd.contextDir = "."
+			if len(args) > 0 {
+				buildCmd.contextDir = args[0]
 			}
+			return buildCmd.run()
+		},
+	}
 
-			c, err := client.GetRegistriesClient(subscription.ID)
-			if err != nil {
-				return fmt.Errorf("could not get registry client: %v", err)
-			}
+	f := cmd.Flags()
+	f.StringVar(&buildCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&buildCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&buildCmd.ignoreFile, "ignore-file", "", "Path to an ignore file to use instead of the context's .dockerignore")
+	f.StringArrayVarP(&buildCmd.imageNames, "image", "t", nil, "The name and tag of the image to build, e.g. 'myapp:v1'")
+	f.StringVarP(&buildCmd.dockerfile, "file", "f", "Dockerfile", "The Dockerfile path relative to the build context")
+	f.StringArrayVar(&buildCmd.buildArgs, "build-arg", nil, "A build argument in KEY=VALUE form (repeatable)")
+	f.StringArrayVar(&buildCmd.secretBuildArgs, "secret-build-arg", nil, "The name of a secret build argument whose value is read from the environment variable of the same name (repeatable)")
+	f.StringVar(&buildCmd.osType, "os", "linux", "The operating system to build on: linux or windows")
+	f.Int32Var(&buildCmd.cpu, "cpu", 0, "The number of CPU cores to build with (defaults to the service's choice)")
+	f.Int32Var(&buildCmd.timeout, "timeout", 600, fmt.Sprintf("The build timeout in seconds (%d-%d)", minTimeout, maxTimeout))
+	f.BoolVar(&buildCmd.noPush, "no-push", false, "Build the image without pushing it to the registry")
+	f.BoolVar(&buildCmd.follow, "follow", false, "Stream the build's logs until it finishes")
+	f.BoolVar(&buildCmd.noWait, "no-wait", false, "Return as soon as the build is queued instead of waiting for it to finish")
 
-			// TODO: make all this configurable...
-			req := containerregistry.QuickBuildRequest{
-				ImageName:      to.StringPtr("acr-builder"),
-				SourceLocation: to.StringPtr("https://bacongobbler.blob.core.windows.net/bacongobbler/master.tar.gz"),
-				BuildArguments: nil,
-				IsPushEnabled:  to.BoolPtr(true),
-				Timeout:        to.Int32Ptr(600),
-				Platform: &containerregistry.PlatformProperties{
-					OsType: containerregistry.Linux,
-					// NB: CPU isn't required right now, possibly want to make this configurable
-					// It'll actually default to 2 from the server
-					// CPU: to.Int32Ptr(1),
-				},
-				DockerFilePath: to.StringPtr("Dockerfile"),
-				Type:           containerregistry.TypeQuickBuild,
-			}
+	return cmd
+}
 
-			fmt.Println("Creating quick build request...")
-			bas, ok := req.AsBasicQueueBuildRequest()
-			if !ok {
-				return errors.New("Failed to create quick build request")
-			}
+func (b *buildCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	req, err := b.newQuickBuildRequest()
+	if err != nil {
+		return validationError(err)
+	}
 
-			fmt.Println("Queuing build...")
-			future, err := c.QueueBuild(ctx, buildCmd.resourceGroupName, buildCmd.registryName, bas)
-			if err != nil {
-				return fmt.Errorf("Errored while queuing build. Err: %v", err)
-			}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
 
-			fmt.Println("Waiting for completion...")
-			err = future.WaitForCompletion(ctx, c.Client)
-			if err != nil {
-				return fmt.Errorf("Errored while waiting for completion")
-			}
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
 
-			fmt.Println()
-			fin, err := future.Result(c)
+	c, err := client.GetRegistriesClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("could not get registry client: %v", err))
+	}
+
+	sourceLocation, err := b.uploadSourceContext(ctx, c)
+	if err != nil {
+		return err
+	}
 
-			fmt.Printf("Build ID: %s\n", *fin.BuildID)
-			fmt.Printf("Build Properties: %v\n", *fin.BuildProperties)
-			fmt.Printf("Build Type: %s\n", *fin.Type)
+	req.SourceLocation = to.StringPtr(sourceLocation)
 
-			return err
-		},
+	fmt.Fprintln(os.Stderr, "Creating quick build request...")
+	bas, ok := req.AsBasicQueueBuildRequest()
+	if !ok {
+		return errors.New("Failed to create quick build request")
 	}
 
-	f := cmd.Flags()
-	f.StringVar(&buildCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
-	f.StringVar(&buildCmd.registryName, "n", "", "The name of the registry")
+	fin, err := queueBuild(ctx, c, b.resourceGroupName, b.registryName, bas)
+	if err != nil {
+		return err
+	}
 
-	return cmd
+	fmt.Fprintf(os.Stderr, "Queued build %s.\n", *fin.BuildID)
+	if b.noWait {
+		return printBuild(p, b.out, fin)
+	}
+
+	bc, err := client.GetBuildsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+	final, err := awaitBuild(context.Background(), bc, b.resourceGroupName, b.registryName, *fin.BuildID, b.follow, logWriter(p, b.out))
+	if err != nil {
+		return err
+	}
+	if err = printBuild(p, b.out, final); err != nil {
+		return err
+	}
+	return buildStatusError(*fin.BuildID, final.Status)
+}
+
+// printBuild writes a build to out using the printer's format.
+func printBuild(p *printer.Printer, out io.Writer, build containerregistry.Build) error {
+	v := view.NewBuild(build)
+	return p.Print(out, v, func(w io.Writer) error {
+		return printBuildDetails(w, v)
+	})
+}
+
+// logWriter returns where streamed build logs go: stdout for human readable
+// output, or stderr so that structured output on stdout stays parseable.
+func logWriter(p *printer.Printer, out io.Writer) io.Writer {
+	if p.IsHumanReadable() {
+		return out
+	}
+	return os.Stderr
 }
 
-func getSubscriptionFromProfile() (*cli.Subscription, error) {
-	profilePath, err := cli.ProfilePath()
+// queueBuild queues a build request and waits for the service to accept it.
+func queueBuild(ctx context.Context, c containerregistry.RegistriesClient, resourceGroupName, registryName string, req containerregistry.BasicQueueBuildRequest) (containerregistry.Build, error) {
+	fmt.Fprintln(os.Stderr, "Queuing build...")
+	future, err := c.QueueBuild(ctx, resourceGroupName, registryName, req)
 	if err != nil {
-		return nil, err
+		return containerregistry.Build{}, wrapAPIError("Errored while queuing build", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Waiting for the build to be queued...")
+	err = future.WaitForCompletion(ctx, c.Client)
+	if err != nil {
+		return containerregistry.Build{}, wrapAPIError("Errored while waiting for completion", err)
 	}
-	profile, err := cli.LoadProfile(profilePath)
+
+	fin, err := future.Result(c)
 	if err != nil {
+		return fin, wrapAPIError("Errored while getting the build result", err)
+	}
+	if fin.BuildProperties == nil || fin.BuildID == nil {
+		return fin, errors.New("The queued build didn't return a build ID")
+	}
+	return fin, nil
+}
+
+// awaitBuild waits for a build to finish, optionally streaming its logs to out,
+// and returns the finished build. Use buildStatusError to turn its status into an exit code.
+func awaitBuild(ctx context.Context, c containerregistry.BuildsClient, resourceGroupName, registryName, buildID string, follow bool, out io.Writer) (containerregistry.Build, error) {
+	if follow {
+		if err := followLogs(ctx, c, resourceGroupName, registryName, buildID, out); err != nil {
+			return containerregistry.Build{}, err
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "Waiting for the build to finish...")
+	}
+
+	for {
+		build, err := c.Get(ctx, resourceGroupName, registryName, buildID)
+		if err != nil {
+			return build, wrapAPIError("Errored while getting the build status", err)
+		}
+		if build.BuildProperties != nil && isTerminalStatus(build.Status) {
+			fmt.Fprintf(os.Stderr, "Build %s finished with status %s\n", buildID, build.Status)
+			return build, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return build, ctx.Err()
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+// newQuickBuildRequest validates the flags and maps them onto a QuickBuildRequest.
+// The source location is filled in once the context has been uploaded.
+func (b *buildCmd) newQuickBuildRequest() (*containerregistry.QuickBuildRequest, error) {
+	if err := validateRegistry(b.resourceGroupName, b.registryName); err != nil {
 		return nil, err
 	}
-	for _, sub := range profile.Subscriptions {
-		if sub.IsDefault {
-			return &sub, nil
+	if b.follow && b.noWait {
+		return nil, errors.New("--follow can't be combined with --no-wait")
+	}
+
+	req := &containerregistry.QuickBuildRequest{
+		IsPushEnabled:  to.BoolPtr(!b.noPush),
+		Timeout:        to.Int32Ptr(b.timeout),
+		DockerFilePath: to.StringPtr(b.dockerfile),
+		Type:           containerregistry.TypeQuickBuild,
+	}
+
+	switch len(b.imageNames) {
+	case 0:
+		if !b.noPush {
+			return nil, errors.New("an image name must be specified with --image unless --no-push is used")
 		}
+	case 1:
+		if err := validateImageName(b.imageNames[0]); err != nil {
+			return nil, err
+		}
+		req.ImageName = to.StringPtr(b.imageNames[0])
+	default:
+		// The 2018-02-01-preview API only accepts a single image name per quick build.
+		return nil, fmt.Errorf("%d images were specified, but quick builds currently support a single --image", len(b.imageNames))
+	}
+
+	if b.dockerfile == "" {
+		return nil, errors.New("the Dockerfile path specified with --file can't be empty")
+	}
+
+	if err := validateTimeout(b.timeout); err != nil {
+		return nil, err
+	}
+
+	platform, err := parsePlatform(b.osType, b.cpu)
+	if err != nil {
+		return nil, err
+	}
+	req.Platform = platform
+
+	buildArgs, err := parseBuildArgs(b.buildArgs, false)
+	if err != nil {
+		return nil, err
+	}
+	secretBuildArgs, err := parseBuildArgs(b.secretBuildArgs, true)
+	if err != nil {
+		return nil, err
+	}
+	if args := append(buildArgs, secretBuildArgs...); len(args) > 0 {
+		req.BuildArguments = &args
+	}
+
+	return req, nil
+}
+
+// uploadSourceContext packages the build context directory, uploads it to the
+// registry's build source storage and returns the relative path to queue the build with.
+func (b *buildCmd) uploadSourceContext(ctx context.Context, c containerregistry.RegistriesClient) (string, error) {
+	fmt.Fprintln(os.Stderr, "Getting source upload URL...")
+	def, err := c.GetBuildSourceUploadURL(ctx, b.resourceGroupName, b.registryName)
+	if err != nil {
+		return "", wrapAPIError("Errored while getting the source upload URL", err)
+	}
+	if def.UploadURL == nil || def.RelativePath == nil {
+		return "", errors.New("The registry returned an incomplete source upload definition")
+	}
+
+	excludes, err := b.readExcludes()
+	if err != nil {
+		return "", fmt.Errorf("Errored while reading the ignore file. Err: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile("", "solstice-context")
+	if err != nil {
+		return "", fmt.Errorf("Errored while creating a temporary file. Err: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	fmt.Fprintf(os.Stderr, "Packing source context %s...\n", b.contextDir)
+	if err = archive.TarGz(b.contextDir, excludes, tmp); err != nil {
+		return "", fmt.Errorf("Errored while packing the source context. Err: %v", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Uploading source context...")
+	if err = blob.UploadFile(ctx, *def.UploadURL, tmp); err != nil {
+		return "", fmt.Errorf("Errored while uploading the source context. Err: %v", err)
+	}
+
+	return *def.RelativePath, nil
+}
+
+// readExcludes returns the exclude patterns for the build context. The Dockerfile,
+// the context's .dockerignore and the --ignore-file, if it's inside the context,
+// are always kept, like the Docker CLI does.
+func (b *buildCmd) readExcludes() ([]string, error) {
+	path := b.ignoreFile
+	if path == "" {
+		path = filepath.Join(b.contextDir, dockerignore.FileName)
+	} else if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	excludes, err := dockerignore.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	excludes = dockerignore.TrimBuildFilesFromExcludes(excludes, b.dockerfile)
+	if b.ignoreFile == "" {
+		return excludes, nil
+	}
+
+	rel, err := relativeToContext(b.contextDir, b.ignoreFile)
+	if err != nil || rel == "" {
+		return excludes, err
+	}
+	if skip, _ := fileutils.Matches(rel, excludes); skip {
+		excludes = append(excludes, "!"+rel)
+	}
+	return excludes, nil
+}
+
+// relativeToContext returns the slash separated path of a file relative to the
+// build context, or an empty string if the file is outside of it.
+func relativeToContext(contextDir, path string) (string, error) {
+	absContext, err := filepath.Abs(contextDir)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absContext, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", nil
 	}
-	return nil, fmt.Errorf("could not find a default subscription ID from %s", profilePath)
+	return filepath.ToSlash(rel), nil
 }
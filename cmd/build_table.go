@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ehotinger/solstice/pkg/view"
+)
+
+const (
+	timeFormatRelative = "relative"
+	timeFormatAbsolute = "absolute"
+)
+
+// buildTable renders builds as a table. Every optional field may be missing,
+// e.g. queued builds have no start time, and is rendered as "-".
+type buildTable struct {
+	wide       bool
+	timeFormat string
+	location   *time.Location
+	now        time.Time
+}
+
+// newBuildTable validates the time rendering flags and creates a buildTable.
+func newBuildTable(wide bool, timeFormat, timezone string) (*buildTable, error) {
+	switch timeFormat {
+	case timeFormatRelative, timeFormatAbsolute:
+	default:
+		return nil, fmt.Errorf("invalid time format %q: it must be either %s or %s", timeFormat, timeFormatRelative, timeFormatAbsolute)
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+	}
+
+	return &buildTable{
+		wide:       wide,
+		timeFormat: timeFormat,
+		location:   loc,
+		now:        time.Now(),
+	}, nil
+}
+
+func (t *buildTable) print(out io.Writer, builds []view.Build) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	header := []string{"BUILD ID", "TASK", "PLATFORM", "STATUS", "TRIGGER", "CREATED", "DURATION", "IMAGES"}
+	if t.wide {
+		header = append(header, "TYPE", "STARTED", "FINISHED", "QUEUE WAIT", "ARCHIVE")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, b := range builds {
+		row := []string{
+			b.ID,
+			orDash(b.BuildTask),
+			t.platform(b.Platform),
+			orDash(b.Status),
+			orDash(b.Trigger),
+			t.timestamp(b.CreateTime),
+			t.duration(b),
+			t.images(b.OutputImages),
+		}
+		if t.wide {
+			row = append(row,
+				orDash(b.BuildType),
+				t.timestamp(b.StartTime),
+				t.timestamp(b.FinishTime),
+				formatDuration(b.QueueWait()),
+				fmt.Sprintf("%t", b.IsArchiveEnabled))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func (t *buildTable) platform(p *view.Platform) string {
+	if p == nil {
+		return "-"
+	}
+	return strings.ToLower(orDash(p.OsType))
+}
+
+func (t *buildTable) timestamp(tm *time.Time) string {
+	if tm == nil {
+		return "-"
+	}
+	if t.timeFormat == timeFormatAbsolute {
+		return tm.In(t.location).Format("2006-01-02 15:04:05 MST")
+	}
+	return humanDuration(t.now.Sub(*tm)) + " ago"
+}
+
+// duration is the run duration of a finished build, or the time elapsed so far for a running one.
+func (t *buildTable) duration(b view.Build) string {
+	if d, ok := b.RunDuration(); ok {
+		return humanDuration(d)
+	}
+	if b.StartTime != nil {
+		return humanDuration(t.now.Sub(*b.StartTime)) + "+"
+	}
+	return "-"
+}
+
+// images lists the output images as repository:tag, adding the digest in wide mode.
+func (t *buildTable) images(images []view.Image) string {
+	if len(images) == 0 {
+		return "-"
+	}
+	var names []string
+	for _, img := range images {
+		name := img.Repository
+		if img.Tag != "" {
+			name += ":" + img.Tag
+		}
+		if t.wide && img.Digest != "" {
+			name += "@" + img.Digest
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+// humanDuration formats a duration with a precision suited to its magnitude, e.g. "45s", "3m", "2h10m" or "4d".
+func humanDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		if s := int(d/time.Second) % 60; s != 0 && d < 10*time.Minute {
+			return fmt.Sprintf("%dm%ds", int(d/time.Minute), s)
+		}
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		if m := int(d/time.Minute) % 60; m != 0 {
+			return fmt.Sprintf("%dh%dm", int(d/time.Hour), m)
+		}
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+}
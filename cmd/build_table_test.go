@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/date"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/pkg/view"
+)
+
+func TestBuildTableUnfinishedBuilds(t *testing.T) {
+	now := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *date.Time { return &date.Time{Time: now.Add(-d)} }
+
+	builds := view.NewBuildList([]containerregistry.Build{
+		// A build without any properties.
+		{Name: to.StringPtr("cb1")},
+		// A queued build has no start or finish time, platform or images yet.
+		{BuildProperties: &containerregistry.BuildProperties{
+			BuildID:    to.StringPtr("cb2"),
+			Status:     containerregistry.Queued,
+			CreateTime: at(time.Minute),
+		}},
+		{BuildProperties: &containerregistry.BuildProperties{
+			BuildID:    to.StringPtr("cb3"),
+			Status:     containerregistry.Running,
+			BuildType:  containerregistry.QuickBuild,
+			CreateTime: at(3 * time.Minute),
+			StartTime:  at(2 * time.Minute),
+		}},
+		{BuildProperties: &containerregistry.BuildProperties{
+			BuildID:    to.StringPtr("cb4"),
+			Status:     containerregistry.Succeeded,
+			BuildTask:  to.StringPtr("webapp"),
+			Trigger:    to.StringPtr("Git Commit"),
+			BuildType:  containerregistry.AutoBuild,
+			Platform:   &containerregistry.PlatformProperties{OsType: containerregistry.Linux},
+			CreateTime: at(time.Hour),
+			StartTime:  at(59 * time.Minute),
+			FinishTime: at(55 * time.Minute),
+			OutputImages: &[]containerregistry.ImageDescriptor{
+				{RepositoryName: to.StringPtr("webapp"), Tag: to.StringPtr("v1"), Digest: to.StringPtr("sha256:abc")},
+			},
+		}},
+	}).Items
+
+	tests := []struct {
+		name string
+		wide bool
+		want [][]string
+	}{
+		{
+			name: "normal",
+			want: [][]string{
+				{"BUILD", "ID", "TASK", "PLATFORM", "STATUS", "TRIGGER", "CREATED", "DURATION", "IMAGES"},
+				{"cb1", "-", "-", "-", "-", "-", "-", "-"},
+				{"cb2", "-", "-", "Queued", "-", "1m", "ago", "-", "-"},
+				{"cb3", "-", "-", "Running", "-", "3m", "ago", "2m+", "-"},
+				{"cb4", "webapp", "linux", "Succeeded", "Git", "Commit", "1h", "ago", "4m", "webapp:v1"},
+			},
+		},
+		{
+			name: "wide",
+			wide: true,
+			want: [][]string{
+				{"BUILD", "ID", "TASK", "PLATFORM", "STATUS", "TRIGGER", "CREATED", "DURATION", "IMAGES", "TYPE", "STARTED", "FINISHED", "QUEUE", "WAIT", "ARCHIVE"},
+				{"cb1", "-", "-", "-", "-", "-", "-", "-", "-", "-", "-", "-", "false"},
+				{"cb2", "-", "-", "Queued", "-", "1m", "ago", "-", "-", "-", "-", "-", "-", "false"},
+				{"cb3", "-", "-", "Running", "-", "3m", "ago", "2m+", "-", "QuickBuild", "2m", "ago", "-", "1m0s", "false"},
+				{"cb4", "webapp", "linux", "Succeeded", "Git", "Commit", "1h", "ago", "4m", "webapp:v1@sha256:abc", "AutoBuild", "59m", "ago", "55m", "ago", "1m0s", "false"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := newBuildTable(tt.wide, timeFormatRelative, "UTC")
+			if err != nil {
+				t.Fatal(err)
+			}
+			table.now = now
+
+			var out bytes.Buffer
+			if err = table.print(&out, builds); err != nil {
+				t.Fatalf("print errored: %v", err)
+			}
+			var got [][]string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				got = append(got, strings.Fields(line))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("print rendered\n%s\nwant the rows\n%q", out.String(), tt.want)
+			}
+		})
+	}
+}
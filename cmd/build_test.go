@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ehotinger/solstice/pkg/fileutils"
+)
+
+func TestReadExcludesKeepsBuildFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solstice-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The custom ignore file excludes everything, itself included.
+	ignoreFile := filepath.Join(dir, "build", "app.dockerignore")
+	if err = os.MkdirAll(filepath.Dir(ignoreFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(ignoreFile, []byte("*\nbuild\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &buildCmd{contextDir: dir, dockerfile: "Dockerfile", ignoreFile: ignoreFile}
+	excludes, err := b.readExcludes()
+	if err != nil {
+		t.Fatalf("readExcludes errored: %v", err)
+	}
+	for _, path := range []string{"Dockerfile", ".dockerignore", "build/app.dockerignore"} {
+		if skip, _ := fileutils.Matches(path, excludes); skip {
+			t.Errorf("%s is excluded by %q", path, excludes)
+		}
+	}
+	if skip, _ := fileutils.Matches("main.go", excludes); !skip {
+		t.Errorf("main.go isn't excluded by %q", excludes)
+	}
+}
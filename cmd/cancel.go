@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const cancelLongMessage = `
+Cancel one or more builds.
+
+Builds are either given by ID, or selected with --status and/or --task, in which
+case every matching build that hasn't finished yet is canceled after confirmation.
+
+Examples:
+  solstice cancel --rg mygroup --n myregistry aa1 aa2
+  solstice cancel --rg mygroup --n myregistry --task mytask --status Running --yes
+`
+
+// Values of view.Cancellation.Result.
+const (
+	cancelRequested = "Requested"
+	cancelDone      = "Canceled"
+	cancelFailed    = "Failed"
+)
+
+type cancelCmd struct {
+	resourceGroupName string
+	registryName      string
+	buildIDs          []string
+	status            string
+	task              string
+	wait              bool
+	yes               bool
+	in                io.Reader
+	out               io.Writer
+}
+
+func newCancelCmd(out io.Writer) *cobra.Command {
+	cancelCmd := &cancelCmd{
+		in:  os.Stdin,
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "cancel [BUILD_ID...]",
+		Short: "Cancel builds",
+		Long:  cancelLongMessage,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cancelCmd.buildIDs = args
+			return cancelCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&cancelCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&cancelCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&cancelCmd.status, "status", "", "Cancel the unfinished builds with this status, e.g. Running or Queued")
+	f.StringVar(&cancelCmd.task, "task", "", "Cancel the unfinished builds of this build task")
+	f.BoolVar(&cancelCmd.wait, "wait", false, "Wait for the cancellations to complete")
+	f.BoolVarP(&cancelCmd.yes, "yes", "y", false, "Don't ask for confirmation")
+
+	return cmd
+}
+
+func (c *cancelCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err := c.validate(); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	bc, err := client.GetBuildsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	buildIDs := c.buildIDs
+	if len(buildIDs) == 0 {
+		if buildIDs, err = c.findBuilds(ctx, bc); err != nil {
+			return err
+		}
+		if len(buildIDs) == 0 {
+			fmt.Fprintln(os.Stderr, "No matching builds to cancel.")
+			return nil
+		}
+
+		if !c.yes {
+			ok, err := confirm(c.in, os.Stderr, fmt.Sprintf("Cancel %d build(s): %v?", len(buildIDs), buildIDs))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Fprintln(os.Stderr, "Aborted.")
+				return nil
+			}
+		}
+	}
+
+	var (
+		results view.CancellationList
+		failed  int
+	)
+	for _, id := range buildIDs {
+		result := c.cancelBuild(ctx, bc, id)
+		if result.Error != "" {
+			failed++
+		}
+		results.Items = append(results.Items, result)
+	}
+
+	err = p.Print(c.out, results, func(w io.Writer) error {
+		for _, r := range results.Items {
+			switch r.Result {
+			case cancelRequested:
+				fmt.Fprintf(w, "Cancellation of build %s requested.\n", r.BuildID)
+			case cancelDone:
+				fmt.Fprintf(w, "Build %s canceled.\n", r.BuildID)
+			default:
+				fmt.Fprintf(w, "Failed to cancel build %s: %s\n", r.BuildID, r.Error)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d build(s) could not be canceled", failed, len(buildIDs))
+	}
+	return nil
+}
+
+func (c *cancelCmd) validate() error {
+	if err := validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return err
+	}
+
+	hasFilter := c.status != "" || c.task != ""
+	if len(c.buildIDs) > 0 && hasFilter {
+		return errors.New("build IDs can't be combined with --status or --task")
+	}
+	if len(c.buildIDs) == 0 && !hasFilter {
+		return errors.New("specify the build IDs to cancel, or select builds with --status and/or --task")
+	}
+
+	if c.status != "" {
+		status, err := parseBuildStatus(c.status)
+		if err != nil {
+			return err
+		}
+		if isTerminalStatus(status) {
+			return fmt.Errorf("builds with status %s have already finished", status)
+		}
+		c.status = string(status)
+	}
+	return nil
+}
+
+// filter returns the $filter selecting the unfinished builds matching the
+// filter flags. Without --status, only unfinished builds are listed, rather than
+// the whole build history.
+func (c *cancelCmd) filter() string {
+	var filter odataFilter
+	if c.status != "" {
+		filter.eq("Status", c.status)
+	} else {
+		filter.eqAny("Status", string(containerregistry.Queued), string(containerregistry.Started), string(containerregistry.Running))
+	}
+	filter.eq("BuildTaskName", c.task)
+	return filter.String()
+}
+
+// findBuilds returns the IDs of the unfinished builds matching the filter flags.
+func (c *cancelCmd) findBuilds(ctx context.Context, bc containerregistry.BuildsClient) ([]string, error) {
+	fmt.Fprintln(os.Stderr, "Listing builds...")
+	iter, err := bc.ListComplete(ctx, c.resourceGroupName, c.registryName, c.filter(), nil, "")
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing builds", err)
+	}
+
+	var ids []string
+	for ; iter.NotDone(); err = iter.Next() {
+		if err != nil {
+			return nil, wrapAPIError("Errored while listing builds", err)
+		}
+		b := iter.Value()
+		if b.BuildProperties == nil || b.BuildID == nil || isTerminalStatus(b.Status) {
+			continue
+		}
+		ids = append(ids, *b.BuildID)
+	}
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing builds", err)
+	}
+	return ids, nil
+}
+
+func (c *cancelCmd) cancelBuild(ctx context.Context, bc containerregistry.BuildsClient, buildID string) view.Cancellation {
+	result := view.Cancellation{BuildID: buildID, Result: cancelFailed}
+
+	future, err := bc.Cancel(ctx, c.resourceGroupName, c.registryName, buildID)
+	if err != nil {
+		result.Error = wrapAPIError("Errored while canceling the build", err).Error()
+		return result
+	}
+
+	if !c.wait {
+		result.Result = cancelRequested
+		return result
+	}
+
+	if err = future.WaitForCompletion(ctx, bc.Client); err != nil {
+		result.Error = wrapAPIError("Errored while waiting for the cancellation", err).Error()
+		return result
+	}
+	if _, err = future.Result(bc); err != nil {
+		result.Error = wrapAPIError("Errored while getting the cancellation result", err).Error()
+		return result
+	}
+	result.Result = cancelDone
+	return result
+}
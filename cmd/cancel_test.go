@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCancelValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        cancelCmd
+		wantErr    string
+		wantFilter string
+	}{
+		{
+			name:    "no registry",
+			cmd:     cancelCmd{registryName: "myregistry", buildIDs: []string{"cb1"}},
+			wantErr: "--rg",
+		},
+		{
+			name: "build IDs",
+			cmd:  cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry", buildIDs: []string{"cb1", "cb2"}},
+		},
+		{
+			name:    "build IDs and status",
+			cmd:     cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry", buildIDs: []string{"cb1"}, status: "Running"},
+			wantErr: "can't be combined",
+		},
+		{
+			name:    "build IDs and task",
+			cmd:     cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry", buildIDs: []string{"cb1"}, task: "webapp"},
+			wantErr: "can't be combined",
+		},
+		{
+			name:    "nothing selected",
+			cmd:     cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry"},
+			wantErr: "specify the build IDs",
+		},
+		{
+			name:       "status",
+			cmd:        cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry", status: "running"},
+			wantFilter: "Status eq 'Running'",
+		},
+		{
+			name:       "task",
+			cmd:        cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry", task: "webapp"},
+			wantFilter: "(Status eq 'Queued' or Status eq 'Started' or Status eq 'Running') and BuildTaskName eq 'webapp'",
+		},
+		{
+			name:       "status and task",
+			cmd:        cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry", status: "Queued", task: "webapp"},
+			wantFilter: "Status eq 'Queued' and BuildTaskName eq 'webapp'",
+		},
+		{
+			name:    "unknown status",
+			cmd:     cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry", status: "Paused"},
+			wantErr: "Paused",
+		},
+		{
+			name:    "terminal status",
+			cmd:     cancelCmd{resourceGroupName: "mygroup", registryName: "myregistry", status: "succeeded"},
+			wantErr: "already finished",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.cmd
+			err := c.validate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validate errored with %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate errored: %v", err)
+			}
+			if tt.wantFilter != "" {
+				if got := c.filter(); got != tt.wantFilter {
+					t.Errorf("filter = %q, want %q", got, tt.wantFilter)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/ehotinger/solstice/iam"
+	"github.com/ehotinger/solstice/pkg/config"
+)
+
+// resolveEnvironment returns the cloud selected with --cloud, AZURE_ENVIRONMENT,
+// AZURE_ARM_ENDPOINT or the configuration file, in that order, defaulting to
+// the public cloud.
+func resolveEnvironment(cloud string) (azure.Environment, error) {
+	if cloud == "" {
+		cloud = os.Getenv("AZURE_ENVIRONMENT")
+	}
+	if cloud == "" {
+		cloud = os.Getenv("AZURE_ARM_ENDPOINT")
+	}
+	if cloud == "" {
+		c, err := config.Load()
+		if err != nil {
+			return azure.Environment{}, err
+		}
+		if iam.IsEnvironmentFile(c.Cloud) {
+			if c.Cloud, err = config.Resolve(c.Cloud); err != nil {
+				return azure.Environment{}, err
+			}
+		}
+		cloud = c.Cloud
+	}
+	return iam.ParseEnvironment(cloud)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by solstice. They are part of the CLI's contract with
+// scripts and are documented in the root command's help and the README.
+const (
+	exitOK                   = 0
+	exitUnexpected           = 1
+	exitValidation           = 2
+	exitAuth                 = 3
+	exitBuildFailed          = 10
+	exitBuildTimeout         = 11
+	exitBuildCanceled        = 12
+	exitBuildSystemAbandoned = 13
+)
+
+const exitCodesMessage = `Exit codes:
+  0   Success, or the build Succeeded
+  1   Unexpected error
+  2   Invalid flags or arguments
+  3   Authentication or authorization failure
+  10  The build Failed
+  11  The build hit its Timeout
+  12  The build was Canceled
+  13  The build was AbandonedAsSystemError
+`
+
+// exitError is an error which carries the exit code solstice should terminate with.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// validationError marks err as caused by invalid user input.
+func validationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitValidation, err: err}
+}
+
+// args wraps a positional arguments validator so that its errors are validation errors.
+func args(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		return validationError(validate(cmd, args))
+	}
+}
+
+// authError marks err as an authentication failure.
+func authError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitAuth, err: err}
+}
+
+// wrapAPIError adds context to an error returned by an Azure API call while
+// keeping authentication failures distinguishable for the exit code.
+func wrapAPIError(message string, err error) error {
+	wrapped := fmt.Errorf("%s. Err: %v", message, err)
+	if isAuthFailure(err) {
+		return authError(wrapped)
+	}
+	return wrapped
+}
+
+// buildStatusError returns an error carrying the exit code for a finished build's
+// status, or nil if the build succeeded.
+func buildStatusError(buildID string, status containerregistry.BuildStatus) error {
+	var code int
+	switch status {
+	case containerregistry.Succeeded:
+		return nil
+	case containerregistry.Failed:
+		code = exitBuildFailed
+	case containerregistry.Timeout:
+		code = exitBuildTimeout
+	case containerregistry.Canceled:
+		code = exitBuildCanceled
+	case containerregistry.AbandonedAsSystemError:
+		code = exitBuildSystemAbandoned
+	default:
+		code = exitUnexpected
+	}
+	return &exitError{code: code, err: fmt.Errorf("build %s finished with status %s", buildID, status)}
+}
+
+// exitCode maps an error returned by a command to the process exit code.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if e, ok := err.(*exitError); ok {
+		return e.code
+	}
+	if isAuthFailure(err) {
+		return exitAuth
+	}
+	return exitUnexpected
+}
+
+// isAuthFailure reports whether err is an autorest error for a 401 or 403
+// response, or for a token which couldn't be acquired or refreshed, e.g. because
+// the credentials expired. The errors wrapped by autorest errors are checked too.
+func isAuthFailure(err error) bool {
+	for err != nil {
+		if _, ok := err.(adal.TokenRefreshError); ok {
+			return true
+		}
+		de, ok := err.(autorest.DetailedError)
+		if !ok {
+			return false
+		}
+		if de.Method == "WithAuthorization" {
+			return true
+		}
+		if code, ok := de.StatusCode.(int); ok && (code == http.StatusUnauthorized || code == http.StatusForbidden) {
+			return true
+		}
+		err = de.Original
+	}
+	return false
+}
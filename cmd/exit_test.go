@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// tokenRefreshError is an adal.TokenRefreshError, like the one returned when
+// AAD rejects the refresh of expired credentials.
+type tokenRefreshError struct{}
+
+func (tokenRefreshError) Error() string { return "adal: Refresh request failed. Status Code = '400'" }
+func (tokenRefreshError) Response() *http.Response {
+	return &http.Response{StatusCode: http.StatusBadRequest}
+}
+
+func TestExitCode(t *testing.T) {
+	refreshFailed := autorest.NewErrorWithError(tokenRefreshError{}, "iam.cachedAuthorizer", "WithAuthorization", nil,
+		"Failed to refresh the token for request to %s", "https://management.azure.com/")
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitOK},
+		{"plain error", errors.New("boom"), exitUnexpected},
+		{"validation", validationError(errors.New("bad flag")), exitValidation},
+		{"auth", authError(errors.New("no token")), exitAuth},
+		{"build failed", buildStatusError("cb1", containerregistry.Failed), exitBuildFailed},
+		{"401", autorest.DetailedError{StatusCode: http.StatusUnauthorized}, exitAuth},
+		{"403", autorest.DetailedError{StatusCode: http.StatusForbidden}, exitAuth},
+		{"404", autorest.DetailedError{StatusCode: http.StatusNotFound}, exitUnexpected},
+		{"token refresh", tokenRefreshError{}, exitAuth},
+		{"authorization", refreshFailed, exitAuth},
+		{"authorization without a refresh error", autorest.DetailedError{Method: "WithAuthorization", Original: errors.New("no token")}, exitAuth},
+		{
+			"authorization while preparing a request",
+			autorest.NewErrorWithError(refreshFailed, "containerregistry.BuildsClient", "Get", nil, "Failure preparing request"),
+			exitAuth,
+		},
+		{
+			"other error while preparing a request",
+			autorest.NewErrorWithError(errors.New("bad URL"), "containerregistry.BuildsClient", "Get", nil, "Failure preparing request"),
+			exitUnexpected,
+		},
+		{"subscription not found", subscriptionError(validationError(errors.New("could not find the subscription"))), exitValidation},
+		{"no subscription", subscriptionError(errors.New("not signed in")), exitAuth},
+		{"wrapped API error", wrapAPIError("Errored while getting log link", refreshFailed), exitAuth},
+		{"wrapped other API error", wrapAPIError("Errored while getting log link", autorest.DetailedError{StatusCode: http.StatusNotFound}), exitUnexpected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// odataFilter accumulates the clauses of an OData $filter expression,
+// as understood by the builds List API (see containerregistry.BuildFilter).
+type odataFilter []string
+
+// eq adds a "name eq 'value'" clause, unless value is empty.
+func (f *odataFilter) eq(name, value string) {
+	if value == "" {
+		return
+	}
+	*f = append(*f, fmt.Sprintf("%s eq '%s'", name, escapeODataString(value)))
+}
+
+// eqAny adds a "(name eq 'a' or name eq 'b')" clause, unless values is empty.
+func (f *odataFilter) eqAny(name string, values ...string) {
+	if len(values) == 0 {
+		return
+	}
+	clauses := make([]string, len(values))
+	for i, v := range values {
+		clauses[i] = fmt.Sprintf("%s eq '%s'", name, escapeODataString(v))
+	}
+	*f = append(*f, "("+strings.Join(clauses, " or ")+")")
+}
+
+// contains adds a "contains(name, 'value')" clause, unless value is empty.
+func (f *odataFilter) contains(name, value string) {
+	if value == "" {
+		return
+	}
+	*f = append(*f, fmt.Sprintf("contains(%s, '%s')", name, escapeODataString(value)))
+}
+
+// compareTime adds a "name op <date-time>" clause, e.g. "CreateTime ge 2018-05-01T00:00:00Z", unless t is zero.
+func (f *odataFilter) compareTime(name, op string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	*f = append(*f, fmt.Sprintf("%s %s %s", name, op, t.UTC().Format(time.RFC3339)))
+}
+
+// String joins the clauses with "and".
+func (f odataFilter) String() string {
+	return strings.Join(f, " and ")
+}
+
+// escapeODataString escapes a value to be used inside a single quoted OData string literal.
+func escapeODataString(s string) string {
+	return strings.Replace(s, "'", "''", -1)
+}
+
+// parseTimeFlag parses a point in time given either as an RFC3339 timestamp or as a
+// duration relative to now, e.g. "2h" meaning two hours ago. An empty string is the zero time.
+func parseTimeFlag(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected an RFC3339 timestamp or a duration like 24h", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListFilter(t *testing.T) {
+	now := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		cmd     listCmd
+		want    string
+		wantErr string
+	}{
+		{
+			name: "no filter",
+		},
+		{
+			name: "quote in task",
+			cmd:  listCmd{task: "it's"},
+			want: "BuildTaskName eq 'it''s'",
+		},
+		{
+			name: "combined",
+			cmd:  listCmd{status: "running", task: "webapp", buildType: "quickbuild", image: "webapp:v1"},
+			want: "Status eq 'Running' and BuildTaskName eq 'webapp' and BuildType eq 'QuickBuild' and contains(OutputImageManifests, 'webapp:v1')",
+		},
+		{
+			name: "relative times",
+			cmd:  listCmd{since: "24h", until: "1h30m"},
+			want: "CreateTime ge 2018-04-30T12:00:00Z and CreateTime le 2018-05-01T10:30:00Z",
+		},
+		{
+			name: "absolute times",
+			cmd:  listCmd{since: "2018-04-01T02:00:00+02:00"},
+			want: "CreateTime ge 2018-04-01T00:00:00Z",
+		},
+		{
+			name:    "until before since",
+			cmd:     listCmd{since: "1h", until: "2h"},
+			wantErr: "--until must not be before --since",
+		},
+		{
+			name:    "invalid time",
+			cmd:     listCmd{since: "yesterday"},
+			wantErr: "invalid time \"yesterday\"",
+		},
+		{
+			name:    "invalid status",
+			cmd:     listCmd{status: "Paused"},
+			wantErr: "Paused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cmd.filter(now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("filter errored with %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filter errored: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("filter = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeODataString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"webapp", "webapp"},
+		{"it's", "it''s"},
+		{"''", "''''"},
+	}
+	for _, tt := range tests {
+		if got := escapeODataString(tt.in); got != tt.want {
+			t.Errorf("escapeODataString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -2,19 +2,49 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"text/tabwriter"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
 	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/view"
 	"github.com/spf13/cobra"
 )
 
+const listLongMessage = `
+List builds.
+
+The --status, --task, --type, --image, --since and --until flags are sent to the
+service as a filter. Only the first page of results is shown unless --all is used.
+
+--since and --until accept an RFC3339 timestamp or a duration relative to now,
+e.g. --since 24h. --sort-by sorts by a column, prefix it with '-' to reverse the
+order: id, status, type, task, trigger, created, started, finished, duration.
+
+Times are shown relative to now ("3m ago") unless --time absolute is used, in
+which case they are rendered in the --timezone time zone (e.g. UTC or
+America/Los_Angeles). Use -o wide for more columns.
+`
+
 type listCmd struct {
 	resourceGroupName string
 	registryName      string
+	status            string
+	task              string
+	buildType         string
+	image             string
+	since             string
+	until             string
+	top               int32
+	all               bool
+	sortBy            string
+	timeFormat        string
+	timezone          string
 	out               io.Writer
 }
 
@@ -26,7 +56,7 @@ func newListCmd(out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List builds",
-		Long:  "List builds",
+		Long:  listLongMessage,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return listCmd.run()
 		},
@@ -35,42 +65,200 @@ func newListCmd(out io.Writer) *cobra.Command {
 	f := cmd.Flags()
 	f.StringVar(&listCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
 	f.StringVar(&listCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&listCmd.status, "status", "", "Only list builds with this status, e.g. Running")
+	f.StringVar(&listCmd.task, "task", "", "Only list builds of this build task")
+	f.StringVar(&listCmd.buildType, "type", "", "Only list builds of this type: QuickBuild or AutoBuild")
+	f.StringVar(&listCmd.image, "image", "", "Only list builds which produced this image, e.g. 'myapp:v1'")
+	f.StringVar(&listCmd.since, "since", "", "Only list builds created after this time")
+	f.StringVar(&listCmd.until, "until", "", "Only list builds created before this time")
+	f.Int32Var(&listCmd.top, "top", 0, "The maximum number of builds to list")
+	f.BoolVar(&listCmd.all, "all", false, "List every page of builds instead of only the first")
+	f.StringVar(&listCmd.sortBy, "sort-by", "", "Sort the builds by a column")
+	f.StringVar(&listCmd.timeFormat, "time", timeFormatRelative, "How to render times: relative or absolute")
+	f.StringVar(&listCmd.timezone, "timezone", "Local", "The time zone absolute times are rendered in")
 
 	return cmd
 }
 
 func (c *listCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return validationError(err)
+	}
+	table, err := newBuildTable(p.Wide(), c.timeFormat, c.timezone)
+	if err != nil {
+		return validationError(err)
+	}
+	filter, err := c.filter(time.Now())
+	if err != nil {
+		return validationError(err)
+	}
+	less, err := buildSortFunc(c.sortBy)
+	if err != nil {
+		return validationError(err)
+	}
+	if c.top < 0 {
+		return validationError(fmt.Errorf("invalid --top %d: it must be a positive number", c.top))
+	}
+
 	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
 	defer cancel()
 
-	subscription, err := getSubscriptionFromProfile()
+	subscription, err := getSubscription()
 	if err != nil {
-		return fmt.Errorf("There was an error while grabbing the subscription: %v", err)
+		return subscriptionError(err)
 	}
 
-	fmt.Println("Getting client...")
+	fmt.Fprintln(os.Stderr, "Getting client...")
 	client, err := client.GetBuildsClient(subscription.ID)
 	if err != nil {
-		return fmt.Errorf("Errored while creating client. Err: %v", err)
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	fmt.Fprintln(os.Stderr, "Listing builds...")
+	builds, err := c.listBuilds(ctx, client, filter)
+	if err != nil {
+		return err
+	}
+
+	list := view.NewBuildList(builds)
+	if less != nil {
+		sort.SliceStable(list.Items, func(i, j int) bool {
+			return less(list.Items[i], list.Items[j])
+		})
+	}
+
+	return p.Print(c.out, list, func(w io.Writer) error {
+		return table.print(w, list.Items)
+	})
+}
+
+// filter compiles the filter flags into an OData $filter expression.
+func (c *listCmd) filter(now time.Time) (string, error) {
+	var filter odataFilter
+
+	if c.status != "" {
+		status, err := parseBuildStatus(c.status)
+		if err != nil {
+			return "", err
+		}
+		filter.eq("Status", string(status))
+	}
+
+	filter.eq("BuildTaskName", c.task)
+
+	if c.buildType != "" {
+		buildType, err := parseBuildType(c.buildType)
+		if err != nil {
+			return "", err
+		}
+		filter.eq("BuildType", string(buildType))
+	}
+
+	filter.contains("OutputImageManifests", c.image)
+
+	since, err := parseTimeFlag(c.since, now)
+	if err != nil {
+		return "", err
+	}
+	until, err := parseTimeFlag(c.until, now)
+	if err != nil {
+		return "", err
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return "", errors.New("--until must not be before --since")
+	}
+	filter.compareTime("CreateTime", "ge", since)
+	filter.compareTime("CreateTime", "le", until)
+
+	return filter.String(), nil
+}
+
+// listBuilds returns the first page of builds, or every page with --all.
+func (c *listCmd) listBuilds(ctx context.Context, bc containerregistry.BuildsClient, filter string) ([]containerregistry.Build, error) {
+	var top *int32
+	if c.top > 0 {
+		top = &c.top
+	}
+
+	if !c.all {
+		page, err := bc.List(ctx, c.resourceGroupName, c.registryName, filter, top, "")
+		if err != nil {
+			return nil, wrapAPIError("Errored while listing builds", err)
+		}
+		return page.Values(), nil
+	}
+
+	iter, err := bc.ListComplete(ctx, c.resourceGroupName, c.registryName, filter, top, "")
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing builds", err)
 	}
 
-	fmt.Println("Listing builds...")
-	page, err := client.List(ctx, c.resourceGroupName, c.registryName, "", nil, "")
+	var builds []containerregistry.Build
+	for ; iter.NotDone(); err = iter.Next() {
+		if err != nil {
+			return nil, wrapAPIError("Errored while listing builds", err)
+		}
+		builds = append(builds, iter.Value())
+		if c.top > 0 && len(builds) >= int(c.top) {
+			return builds, nil
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("Errored while listing builds. Err: %v", err)
+		return nil, wrapAPIError("Errored while listing builds", err)
 	}
+	return builds, nil
+}
 
-	w := new(tabwriter.Writer)
+// buildSortKeys maps the --sort-by column names to an ascending comparison.
+var buildSortKeys = map[string]func(a, b view.Build) bool{
+	"id":       func(a, b view.Build) bool { return a.ID < b.ID },
+	"status":   func(a, b view.Build) bool { return a.Status < b.Status },
+	"type":     func(a, b view.Build) bool { return a.BuildType < b.BuildType },
+	"task":     func(a, b view.Build) bool { return a.BuildTask < b.BuildTask },
+	"trigger":  func(a, b view.Build) bool { return a.Trigger < b.Trigger },
+	"created":  func(a, b view.Build) bool { return timeLess(a.CreateTime, b.CreateTime) },
+	"started":  func(a, b view.Build) bool { return timeLess(a.StartTime, b.StartTime) },
+	"finished": func(a, b view.Build) bool { return timeLess(a.FinishTime, b.FinishTime) },
+	"duration": func(a, b view.Build) bool {
+		da, _ := a.RunDuration()
+		db, _ := b.RunDuration()
+		return da < db
+	},
+}
+
+// buildSortFunc returns the comparison for a --sort-by value, or nil to keep the service's order.
+func buildSortFunc(sortBy string) (func(a, b view.Build) bool, error) {
+	if sortBy == "" {
+		return nil, nil
+	}
 
-	// Format in tab-separated columns with a tab stop of 8.
-	w.Init(os.Stdout, 0, 8, 0, '\t', 0)
-	fmt.Fprintln(w, "Build ID\tCreate Time\tStart Time\tFinish Time")
+	key := strings.ToLower(sortBy)
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
 
-	builds := page.Values()
-	for _, b := range builds {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", *b.BuildID, *b.CreateTime, *b.StartTime, *b.FinishTime)
+	less, ok := buildSortKeys[key]
+	if !ok {
+		var keys []string
+		for k := range buildSortKeys {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("invalid --sort-by %q: it must be one of %s", sortBy, strings.Join(keys, ", "))
 	}
+	if desc {
+		return func(a, b view.Build) bool { return less(b, a) }, nil
+	}
+	return less, nil
+}
 
-	w.Flush()
-	return err
+// timeLess orders unknown times before known ones.
+func timeLess(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	return a.Before(*b)
 }
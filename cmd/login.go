@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure/cli"
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/iam"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const loginLongMessage = `
+Sign in to Azure and cache the credentials for later commands.
+
+Users sign in with a device code: open the URL shown on stderr and enter the
+code. Service principals sign in with --service-principal and the credentials
+given with --tenant-id, --client-id and --client-secret-file or
+--certificate-file, or the matching environment variables.
+
+The credentials are cached in ~/.solstice/tokens.json, readable by the current
+user only, and are used instead of the az CLI's tokens until 'solstice logout'.
+For service principals, the client secret or the certificate's path and
+password are cached, like 'az login' does. Each cloud selected with --cloud
+has its own account.
+
+The subscriptions of the account are listed and saved; the first enabled one is
+the default unless --subscription selects another one by ID or name. Nothing is
+saved if --subscription matches none of them.
+
+Examples:
+  solstice login
+  solstice login --tenant-id contoso.onmicrosoft.com
+  solstice login --service-principal --tenant-id <tenant> --client-id <app ID> \
+    --client-secret-file secret.txt
+`
+
+type loginCmd struct {
+	servicePrincipal bool
+	out              io.Writer
+}
+
+func newLoginCmd(out io.Writer) *cobra.Command {
+	loginCmd := &loginCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Sign in to Azure",
+		Long:  loginLongMessage,
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return loginCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&loginCmd.servicePrincipal, "service-principal", false, "Sign in as the service principal given with --tenant-id, --client-id and --client-secret-file or --certificate-file")
+
+	return cmd
+}
+
+func (c *loginCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+
+	account := view.Account{
+		Type:          "user",
+		Cloud:         iam.Environment().Name,
+		Subscriptions: []view.Subscription{},
+	}
+	if c.servicePrincipal {
+		account.Type = "servicePrincipal"
+		account.Name, err = iam.LoginServicePrincipal()
+	} else {
+		account.Name, err = iam.LoginWithDeviceCode(iam.TenantID(), os.Stderr)
+	}
+	if err != nil {
+		return authError(fmt.Errorf("Errored while signing in. Err: %v", err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancel()
+
+	subs, err := client.ListSubscriptions(ctx, "")
+	if err != nil {
+		return wrapAPIError("Errored while listing subscriptions", err)
+	}
+	saved := make([]cli.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		saved = append(saved, cli.Subscription{
+			EnvironmentName: iam.Environment().Name,
+			ID:              sub.ID,
+			Name:            sub.Name,
+			State:           sub.State,
+		})
+	}
+	if err = markDefaultSubscription(saved, settings.subscription); err != nil {
+		return validationError(err)
+	}
+	if err = iam.SetLoginSubscriptions(saved); err != nil {
+		return fmt.Errorf("Errored while saving the subscriptions. Err: %v", err)
+	}
+	for _, sub := range saved {
+		account.Subscriptions = append(account.Subscriptions, view.Subscription{
+			ID:        sub.ID,
+			Name:      sub.Name,
+			State:     sub.State,
+			IsDefault: sub.IsDefault,
+		})
+	}
+
+	return p.Print(c.out, account, func(out io.Writer) error {
+		fmt.Fprintf(out, "Logged in to %s as %s.\n", account.Cloud, account.Name)
+		if len(account.Subscriptions) == 0 {
+			_, err := fmt.Fprintln(out, "The account has no subscriptions.")
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "SUBSCRIPTION ID\tNAME\tSTATE\tDEFAULT")
+		for _, sub := range account.Subscriptions {
+			def := ""
+			if sub.IsDefault {
+				def = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", sub.ID, sub.Name, sub.State, def)
+		}
+		return w.Flush()
+	})
+}
+
+// markDefaultSubscription marks the subscription selected by ID or name as the
+// default, or the first enabled subscription if none is selected. A selection
+// which matches no subscription, or several by name, is an error.
+func markDefaultSubscription(subs []cli.Subscription, selector string) error {
+	def := -1
+	if selector != "" {
+		var matches []int
+		for i, sub := range subs {
+			if strings.EqualFold(sub.ID, selector) {
+				matches = []int{i}
+				break
+			}
+			if strings.EqualFold(sub.Name, selector) {
+				matches = append(matches, i)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return fmt.Errorf("could not find the subscription %q from --subscription.\n%s", selector, listSubscriptions(subs))
+		case 1:
+			def = matches[0]
+		default:
+			ambiguous := make([]cli.Subscription, len(matches))
+			for i, m := range matches {
+				ambiguous[i] = subs[m]
+			}
+			return fmt.Errorf("the subscription name %q from --subscription is ambiguous, select the subscription by ID instead.\n%s", selector, listSubscriptions(ambiguous))
+		}
+	}
+	for i, sub := range subs {
+		if def < 0 && strings.EqualFold(sub.State, "Enabled") {
+			def = i
+		}
+	}
+	if def < 0 && len(subs) > 0 {
+		def = 0
+	}
+	for i := range subs {
+		subs[i].IsDefault = i == def
+	}
+	return nil
+}
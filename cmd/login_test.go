@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure/cli"
+)
+
+func TestMarkDefaultSubscription(t *testing.T) {
+	newSubs := func() []cli.Subscription {
+		return []cli.Subscription{
+			{ID: "00000000-0000-0000-0000-000000000001", Name: "dev", State: "Disabled"},
+			{ID: "00000000-0000-0000-0000-000000000002", Name: "test", State: "Enabled"},
+			{ID: "00000000-0000-0000-0000-000000000003", Name: "prod", State: "Enabled"},
+			{ID: "00000000-0000-0000-0000-000000000004", Name: "prod", State: "Enabled"},
+		}
+	}
+	tests := []struct {
+		name        string
+		selector    string
+		wantDefault string
+		wantErr     string
+	}{
+		{"first enabled", "", "00000000-0000-0000-0000-000000000002", ""},
+		{"by ID", "00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000001", ""},
+		{"by name", "TEST", "00000000-0000-0000-0000-000000000002", ""},
+		{"unknown", "staging", "", "could not find the subscription \"staging\""},
+		{"ambiguous name", "prod", "", "is ambiguous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subs := newSubs()
+			err := markDefaultSubscription(subs, tt.selector)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("markDefaultSubscription errored with %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("markDefaultSubscription errored: %v", err)
+			}
+			for _, sub := range subs {
+				if sub.IsDefault != (sub.ID == tt.wantDefault) {
+					t.Errorf("subscription %s: IsDefault = %v", sub.ID, sub.IsDefault)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ehotinger/solstice/iam"
+	"github.com/spf13/cobra"
+)
+
+const logoutLongMessage = `
+Remove the credentials cached by 'solstice login' for the cloud selected with
+--cloud. Later commands use the az CLI's tokens again, unless other
+credentials are configured.
+`
+
+type logoutCmd struct {
+	out io.Writer
+}
+
+func newLogoutCmd(out io.Writer) *cobra.Command {
+	logoutCmd := &logoutCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the cached credentials",
+		Long:  logoutLongMessage,
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return logoutCmd.run()
+		},
+	}
+
+	return cmd
+}
+
+func (c *logoutCmd) run() error {
+	name, err := iam.Logout()
+	if err != nil {
+		return fmt.Errorf("Errored while removing the cached credentials. Err: %v", err)
+	}
+	if name == "" {
+		_, err = fmt.Fprintf(c.out, "Not logged in to %s.\n", iam.Environment().Name)
+		return err
+	}
+	_, err = fmt.Fprintf(c.out, "Logged out %s from %s.\n", name, iam.Environment().Name)
+	return err
+}
@@ -5,21 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
 	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
 	"github.com/ehotinger/solstice/client"
 	"github.com/ehotinger/solstice/pkg/blob"
 	"github.com/spf13/cobra"
 )
 
+// logPollInterval is how often the log blob and build status are polled when following a build.
+const logPollInterval = time.Second * 2
+
 type logsCmd struct {
 	resourceGroupName string
 	registryName      string
 	buildID           string
+	follow            bool
 	out               io.Writer
 }
 
@@ -41,37 +45,43 @@ func newLogsCmd(out io.Writer) *cobra.Command {
 	f.StringVar(&logsCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
 	f.StringVar(&logsCmd.registryName, "n", "", "The name of the registry")
 	f.StringVar(&logsCmd.buildID, "b", "", "The build id to look for logs")
+	f.BoolVar(&logsCmd.follow, "follow", false, "Stream the logs until the build finishes")
 
 	return cmd
 }
 
 func (cmd *logsCmd) run() error {
-	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
-	defer cancel()
+	if err := validateRegistry(cmd.resourceGroupName, cmd.registryName); err != nil {
+		return validationError(err)
+	}
+	if cmd.buildID == "" {
+		return validationError(errors.New("a build ID must be specified with -b"))
+	}
 
-	subscription, err := getSubscriptionFromProfile()
+	subscription, err := getSubscription()
 	if err != nil {
-		return fmt.Errorf("There was an error while grabbing the subscription: %v", err)
+		return subscriptionError(err)
 	}
 
-	fmt.Println("Getting client...")
+	fmt.Fprintln(os.Stderr, "Getting client...")
 	c, err := client.GetBuildsClient(subscription.ID)
 	if err != nil {
-		return fmt.Errorf("Errored while creating client. Err: %v", err)
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
 	}
 
-	logResult, err := c.GetLogLink(ctx, cmd.resourceGroupName, cmd.registryName, cmd.buildID)
-	if err != nil {
-		return fmt.Errorf("Errored while getting log link. Err: %v", err)
+	if cmd.follow {
+		return followLogs(context.Background(), c, cmd.resourceGroupName, cmd.registryName, cmd.buildID, cmd.out)
 	}
 
-	logSAS := *logResult.LogLink
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancel()
 
-	if logSAS == "" {
-		return errors.New("Unable to create a link to the logs")
+	logSAS, err := getLogLink(ctx, c, cmd.resourceGroupName, cmd.registryName, cmd.buildID)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Log URL: %s\n", logSAS)
+	fmt.Fprintf(os.Stderr, "Log URL: %s\n", logSAS)
 	blobURL := blob.GetAppendBlobURL(logSAS)
 
 	contentLength := int64(0) // Used for progress reporting to report the total number of bytes being downloaded.
@@ -93,15 +103,46 @@ func (cmd *logsCmd) run() error {
 	// NewResponseBodyStream wraps the GetRetryStream with progress reporting; it returns an io.ReadCloser.
 	stream := pipeline.NewResponseBodyProgress(rs,
 		func(bytesTransferred int64) {
-			fmt.Printf("Downloaded %d of %d bytes.\n", bytesTransferred, contentLength)
+			fmt.Fprintf(os.Stderr, "Downloaded %d of %d bytes.\n", bytesTransferred, contentLength)
 		})
 	defer stream.Close() // The client must close the response body when finished with it
 
-	fmt.Println("\nLogs:")
-	_, err = io.Copy(os.Stdout, stream)
+	fmt.Fprintln(os.Stderr, "\nLogs:")
+	if _, err = io.Copy(cmd.out, stream); err != nil {
+		return fmt.Errorf("Errored while downloading the logs. Err: %v", err)
+	}
+	return nil
+}
+
+// getLogLink returns the SAS URL of the log blob for a build.
+func getLogLink(ctx context.Context, c containerregistry.BuildsClient, resourceGroupName, registryName, buildID string) (string, error) {
+	logResult, err := c.GetLogLink(ctx, resourceGroupName, registryName, buildID)
+	if err != nil {
+		return "", wrapAPIError("Errored while getting log link", err)
+	}
+
+	if logResult.LogLink == nil || *logResult.LogLink == "" {
+		return "", errors.New("Unable to create a link to the logs")
+	}
+	return *logResult.LogLink, nil
+}
+
+// followLogs streams the log of a build to out as it is written and returns once
+// the build has reached a terminal status and the whole log has been copied.
+func followLogs(ctx context.Context, c containerregistry.BuildsClient, resourceGroupName, registryName, buildID string, out io.Writer) error {
+	logSAS, err := getLogLink(ctx, c, resourceGroupName, registryName, buildID)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Streaming logs...")
+	done := func() (bool, error) {
+		build, err := c.Get(ctx, resourceGroupName, registryName, buildID)
+		if err != nil {
+			return false, wrapAPIError("Errored while getting the build status", err)
+		}
+		return build.BuildProperties != nil && isTerminalStatus(build.Status), nil
 	}
 
-	return err
+	return blob.FollowAppendBlob(ctx, blob.GetAppendBlobURL(logSAS), out, logPollInterval, done)
 }
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+const (
+	// buildArgumentType is the only build argument type the service understands.
+	buildArgumentType = "DockerBuildArgument"
+
+	minTimeout = 300
+	maxTimeout = 28800
+)
+
+var (
+	registryNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]{5,50}$`)
+
+	// imageNameRegexp matches a repository name with an optional tag, e.g. "foo/bar:v1".
+	imageNameRegexp = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*(?::[\w][\w.-]{0,127})?$`)
+)
+
+// validateRegistry checks the resource group and registry name flags shared by most commands.
+func validateRegistry(resourceGroupName, registryName string) error {
+	if resourceGroupName == "" {
+		return fmt.Errorf("a resource group must be specified with --rg")
+	}
+	if registryName == "" {
+		return fmt.Errorf("a registry name must be specified with --n")
+	}
+	if !registryNameRegexp.MatchString(registryName) {
+		return fmt.Errorf("invalid registry name %q: it must be 5-50 alphanumeric characters", registryName)
+	}
+	return nil
+}
+
+// validateImageName checks that name is a repository with an optional tag.
+func validateImageName(name string) error {
+	if !imageNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid image name %q: expected a lowercase repository with an optional tag, e.g. 'myapp:v1'", name)
+	}
+	return nil
+}
+
+// validateTimeout checks a build timeout, in seconds, against the range accepted by the service.
+func validateTimeout(timeout int32) error {
+	if timeout < minTimeout || timeout > maxTimeout {
+		return fmt.Errorf("invalid timeout %d: it must be between %d and %d seconds", timeout, minTimeout, maxTimeout)
+	}
+	return nil
+}
+
+// parsePlatform converts the --os and --cpu flag values into PlatformProperties.
+// A cpu of 0 leaves the choice to the service.
+func parsePlatform(osType string, cpu int32) (*containerregistry.PlatformProperties, error) {
+	p := &containerregistry.PlatformProperties{}
+	switch strings.ToLower(osType) {
+	case "linux":
+		p.OsType = containerregistry.Linux
+	case "windows":
+		p.OsType = containerregistry.Windows
+	default:
+		return nil, fmt.Errorf("invalid os %q: it must be either linux or windows", osType)
+	}
+
+	if cpu < 0 {
+		return nil, fmt.Errorf("invalid cpu count %d: it must be a positive number", cpu)
+	}
+	if cpu > 0 {
+		p.CPU = to.Int32Ptr(cpu)
+	}
+	return p, nil
+}
+
+// parseBuildArgs converts KEY=VALUE pairs into build arguments. Like 'docker build',
+// a bare KEY takes its value from the environment and is skipped if it isn't set.
+// Secret build arguments are only taken from the environment, so that their
+// values never appear on the command line.
+func parseBuildArgs(args []string, isSecret bool) ([]containerregistry.BuildArgument, error) {
+	var result []containerregistry.BuildArgument
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		name := kv[0]
+		// Don't echo the whole argument, its value may be a secret.
+		if err := validateBuildArgName(name); err != nil {
+			return nil, err
+		}
+
+		var value string
+		if isSecret {
+			if len(kv) == 2 {
+				return nil, fmt.Errorf("invalid secret build argument %s: its value must not be given on the command line, set the %s environment variable and pass %s alone", name, name, name)
+			}
+			v, err := readSecretEnv(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid secret build argument %s: %v", name, err)
+			}
+			value = v
+		} else if len(kv) == 2 {
+			value = kv[1]
+		} else {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			value = v
+		}
+
+		result = append(result, newBuildArg(name, value, isSecret))
+	}
+	return result, nil
+}
+
+// validateBuildArgName checks the name of a build argument.
+func validateBuildArgName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid build argument name %q: expected KEY=VALUE", name)
+	}
+	return nil
+}
+
+func newBuildArg(name, value string, isSecret bool) containerregistry.BuildArgument {
+	return containerregistry.BuildArgument{
+		Type:     to.StringPtr(buildArgumentType),
+		Name:     to.StringPtr(name),
+		Value:    to.StringPtr(value),
+		IsSecret: to.BoolPtr(isSecret),
+	}
+}
@@ -0,0 +1,545 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/pkg/manifest"
+	"github.com/ehotinger/solstice/pkg/view"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Values of view.PlanAction.Action.
+const (
+	planCreate  = "create"
+	planUpdate  = "update"
+	planReplace = "replace"
+	planDelete  = "delete"
+)
+
+// Values of view.PlanAction.Kind.
+const (
+	kindBuildTask = "BuildTask"
+	kindBuildStep = "BuildStep"
+)
+
+// plannedChange is a change of a plan, along with the manifest entries it converges to.
+// Deletions have neither. Task updates also carry the current task.
+type plannedChange struct {
+	view.PlanAction
+	task    *manifest.BuildTask
+	step    *manifest.Step
+	current *containerregistry.BuildTask
+}
+
+// registryState is the current state of a registry: all of its build tasks, and
+// the steps of those which are part of the manifest. Both are keyed by lower
+// case name, since names are case insensitive.
+type registryState struct {
+	tasks map[string]containerregistry.BuildTask
+	steps map[string]map[string]containerregistry.BuildStep
+}
+
+// planner compares a manifest with the state of a registry.
+type planner struct {
+	// dir is the directory of the manifest, which relative paths are resolved against.
+	dir   string
+	prune bool
+	// createOnly leaves existing build tasks and steps alone, only creating missing ones.
+	createOnly bool
+}
+
+// fetchRegistryState reads the build tasks of a registry, and the steps of the tasks in m.
+func fetchRegistryState(ctx context.Context, tc containerregistry.BuildTasksClient, sc containerregistry.BuildStepsClient, resourceGroupName, registryName string, m *manifest.Manifest) (*registryState, error) {
+	tasks, err := listTasks(ctx, tc, resourceGroupName, registryName)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &registryState{
+		tasks: make(map[string]containerregistry.BuildTask, len(tasks)),
+		steps: make(map[string]map[string]containerregistry.BuildStep),
+	}
+	for _, t := range tasks {
+		state.tasks[strings.ToLower(to.String(t.Name))] = t
+	}
+
+	for _, t := range m.BuildTasks {
+		key := strings.ToLower(t.Name)
+		if _, ok := state.tasks[key]; !ok {
+			continue
+		}
+		steps, err := listSteps(ctx, sc, resourceGroupName, registryName, t.Name)
+		if err != nil {
+			return nil, err
+		}
+		state.steps[key] = make(map[string]containerregistry.BuildStep, len(steps))
+		for _, s := range steps {
+			state.steps[key][strings.ToLower(to.String(s.Name))] = s
+		}
+	}
+	return state, nil
+}
+
+// plan returns the changes which converge the registry to the manifest, in the
+// order they have to be made in. Build tasks and steps which aren't part of the
+// manifest are only deleted when pruning.
+func (p *planner) plan(m *manifest.Manifest, state *registryState) ([]plannedChange, error) {
+	var changes []plannedChange
+	wanted := make(map[string]bool, len(m.BuildTasks))
+
+	for i := range m.BuildTasks {
+		t := &m.BuildTasks[i]
+		key := strings.ToLower(t.Name)
+		wanted[key] = true
+
+		actual, exists := state.tasks[key]
+		if !exists {
+			if err := checkTokenSource(t); err != nil {
+				return nil, err
+			}
+			changes = append(changes, newTaskChange(planCreate, t, nil))
+			changes = append(changes, p.createSteps(t)...)
+			continue
+		}
+
+		if p.createOnly {
+			changes = append(changes, p.createMissingSteps(t, state.steps[key])...)
+			continue
+		}
+
+		diff, replace, err := diffTask(t, actual)
+		if err != nil {
+			return nil, fmt.Errorf("build task %s: %v", t.Name, err)
+		}
+		if replace {
+			// Replacing a task deletes its steps, so they're all created again.
+			if err := checkTokenSource(t); err != nil {
+				return nil, err
+			}
+			changes = append(changes, newTaskChange(planReplace, t, diff))
+			changes = append(changes, p.createSteps(t)...)
+			continue
+		}
+		if len(diff) > 0 {
+			change := newTaskChange(planUpdate, t, diff)
+			change.current = &actual
+			changes = append(changes, change)
+		}
+
+		stepChanges, err := p.planSteps(t, state.steps[key])
+		if err != nil {
+			return nil, fmt.Errorf("build task %s: %v", t.Name, err)
+		}
+		changes = append(changes, stepChanges...)
+	}
+
+	if p.prune {
+		var extra []string
+		for key, t := range state.tasks {
+			if !wanted[key] {
+				extra = append(extra, to.String(t.Name))
+			}
+		}
+		sort.Strings(extra)
+		for _, name := range extra {
+			changes = append(changes, plannedChange{
+				PlanAction: view.PlanAction{Action: planDelete, Kind: kindBuildTask, BuildTask: name},
+			})
+		}
+	}
+	return changes, nil
+}
+
+func (p *planner) createSteps(t *manifest.BuildTask) []plannedChange {
+	var changes []plannedChange
+	for i := range t.Steps {
+		changes = append(changes, newStepChange(planCreate, t, &t.Steps[i], nil))
+	}
+	return changes
+}
+
+// createMissingSteps creates the steps of an existing build task which don't exist yet.
+func (p *planner) createMissingSteps(t *manifest.BuildTask, actual map[string]containerregistry.BuildStep) []plannedChange {
+	var changes []plannedChange
+	for i := range t.Steps {
+		if _, exists := actual[strings.ToLower(t.Steps[i].Name)]; !exists {
+			changes = append(changes, newStepChange(planCreate, t, &t.Steps[i], nil))
+		}
+	}
+	return changes
+}
+
+// planSteps compares the steps of a build task which already exists.
+func (p *planner) planSteps(t *manifest.BuildTask, actual map[string]containerregistry.BuildStep) ([]plannedChange, error) {
+	var changes []plannedChange
+	wanted := make(map[string]bool, len(t.Steps))
+
+	for i := range t.Steps {
+		s := &t.Steps[i]
+		key := strings.ToLower(s.Name)
+		wanted[key] = true
+
+		current, exists := actual[key]
+		if !exists {
+			changes = append(changes, newStepChange(planCreate, t, s, nil))
+			continue
+		}
+
+		diff, replace, err := p.diffStep(s, current)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %v", s.Name, err)
+		}
+		switch {
+		case replace:
+			changes = append(changes, newStepChange(planReplace, t, s, diff))
+		case len(diff) > 0:
+			changes = append(changes, newStepChange(planUpdate, t, s, diff))
+		}
+	}
+
+	if p.prune {
+		var extra []string
+		for key, s := range actual {
+			if !wanted[key] {
+				extra = append(extra, to.String(s.Name))
+			}
+		}
+		sort.Strings(extra)
+		for _, name := range extra {
+			changes = append(changes, plannedChange{
+				PlanAction: view.PlanAction{Action: planDelete, Kind: kindBuildStep, BuildTask: t.Name, Step: name},
+			})
+		}
+	}
+	return changes, nil
+}
+
+func newTaskChange(action string, t *manifest.BuildTask, diff []string) plannedChange {
+	return plannedChange{
+		PlanAction: view.PlanAction{Action: action, Kind: kindBuildTask, BuildTask: t.Name, Changes: diff},
+		task:       t,
+	}
+}
+
+func newStepChange(action string, t *manifest.BuildTask, s *manifest.Step, diff []string) plannedChange {
+	return plannedChange{
+		PlanAction: view.PlanAction{Action: action, Kind: kindBuildStep, BuildTask: t.Name, Step: s.Name, Changes: diff},
+		task:       t,
+		step:       s,
+	}
+}
+
+// checkTokenSource makes sure that a build task which has to be created has a source control token.
+func checkTokenSource(t *manifest.BuildTask) error {
+	if t.Source.TokenFile == "" && t.Source.TokenEnv == "" {
+		return fmt.Errorf("build task %s has to be created, but its source has neither a tokenFile nor a tokenEnv", t.Name)
+	}
+	return nil
+}
+
+// diffTask lists the differences between a build task and its manifest entry.
+// Changes of the location or the source repository can't be made in place, so
+// they require the task to be replaced.
+func diffTask(t *manifest.BuildTask, actual containerregistry.BuildTask) (diff []string, replace bool, err error) {
+	props := actual.BuildTaskProperties
+	if props == nil {
+		props = &containerregistry.BuildTaskProperties{}
+	}
+	source := props.SourceRepository
+	if source == nil {
+		source = &containerregistry.SourceRepositoryProperties{}
+	}
+
+	sourceControlType, err := parseSourceControlType(t.Source.Type)
+	if err != nil {
+		return nil, false, err
+	}
+	if normalizeLocation(to.String(actual.Location)) != normalizeLocation(t.Location) {
+		diff = append(diff, describeChange("location", to.String(actual.Location), t.Location))
+	}
+	if source.SourceControlType != sourceControlType {
+		diff = append(diff, describeChange("source.type", string(source.SourceControlType), string(sourceControlType)))
+	}
+	if !sameRepositoryURL(to.String(source.RepositoryURL), t.Source.RepositoryURL) {
+		diff = append(diff, describeChange("source.repositoryUrl", to.String(source.RepositoryURL), t.Source.RepositoryURL))
+	}
+	if len(diff) > 0 {
+		return diff, true, nil
+	}
+
+	if t.Alias != "" && t.Alias != to.String(props.Alias) {
+		diff = append(diff, describeChange("alias", to.String(props.Alias), t.Alias))
+	}
+	if t.Status != "" {
+		status, err := parseTaskStatus(t.Status)
+		if err != nil {
+			return nil, false, err
+		}
+		if status != props.Status {
+			diff = append(diff, describeChange("status", string(props.Status), string(status)))
+		}
+	}
+	if t.Platform != nil {
+		platform, err := manifestPlatform(t.Platform)
+		if err != nil {
+			return nil, false, err
+		}
+		current := props.Platform
+		if current == nil {
+			current = &containerregistry.PlatformProperties{}
+		}
+		if !strings.EqualFold(string(current.OsType), string(platform.OsType)) {
+			diff = append(diff, describeChange("platform.os", string(current.OsType), string(platform.OsType)))
+		}
+		if platform.CPU != nil && to.Int32(current.CPU) != *platform.CPU {
+			diff = append(diff, describeChange("platform.cpu", fmt.Sprint(to.Int32(current.CPU)), fmt.Sprint(*platform.CPU)))
+		}
+	}
+	if t.Timeout != nil {
+		if err := validateTimeout(*t.Timeout); err != nil {
+			return nil, false, err
+		}
+		if to.Int32(props.Timeout) != *t.Timeout {
+			diff = append(diff, describeChange("timeout", fmt.Sprint(to.Int32(props.Timeout)), fmt.Sprint(*t.Timeout)))
+		}
+	}
+	if t.Tags != nil && !sameTags(actual.Tags, t.Tags) {
+		diff = append(diff, "tags")
+	}
+	if t.Source.CommitTrigger != nil && to.Bool(source.IsCommitTriggerEnabled) != *t.Source.CommitTrigger {
+		diff = append(diff, describeChange("source.commitTrigger", fmt.Sprint(to.Bool(source.IsCommitTriggerEnabled)), fmt.Sprint(*t.Source.CommitTrigger)))
+	}
+	return diff, false, nil
+}
+
+// diffStep lists the differences between a build step and its manifest entry.
+// Steps which aren't Docker build steps, or whose base images changed, have to
+// be replaced. The values of secret build arguments are never compared.
+func (p *planner) diffStep(s *manifest.Step, actual containerregistry.BuildStep) (diff []string, replace bool, err error) {
+	if actual.BasicBuildStepProperties == nil {
+		return []string{"type"}, true, nil
+	}
+	d, ok := actual.BasicBuildStepProperties.AsDockerBuildStep()
+	if !ok {
+		return []string{describeChange("type", "-", string(containerregistry.TypeDocker))}, true, nil
+	}
+
+	compare := func(field, current, wanted string) {
+		if wanted != "" && wanted != current {
+			diff = append(diff, describeChange(field, current, wanted))
+		}
+	}
+	compare("branch", to.String(d.Branch), s.Branch)
+	if s.Image != "" {
+		if err := validateImageName(s.Image); err != nil {
+			return nil, false, err
+		}
+	}
+	compare("image", to.String(d.ImageName), s.Image)
+	compare("dockerfile", to.String(d.DockerFilePath), s.Dockerfile)
+	compare("context", to.String(d.ContextPath), s.Context)
+	if s.Push != nil && to.Bool(d.IsPushEnabled) != *s.Push {
+		diff = append(diff, describeChange("push", fmt.Sprint(to.Bool(d.IsPushEnabled)), fmt.Sprint(*s.Push)))
+	}
+	if s.BaseImageTrigger != "" {
+		trigger, err := parseBaseImageTrigger(s.BaseImageTrigger)
+		if err != nil {
+			return nil, false, err
+		}
+		compare("baseImageTrigger", string(d.BaseImageTrigger), string(trigger))
+	}
+	if s.BaseImages != nil {
+		// Base images can't be updated in place.
+		wanted, err := parseBaseImageDependencies(s.BaseImages, true)
+		if err != nil {
+			return nil, false, err
+		}
+		var current []containerregistry.BaseImageDependency
+		if d.BaseImageDependencies != nil {
+			current = *d.BaseImageDependencies
+		}
+		if before, after := baseImageList(current), baseImageList(wanted); before != after {
+			diff = append(diff, describeChange("baseImages", orDash(before), orDash(after)))
+			replace = true
+		}
+	}
+
+	if s.BuildArgs != nil {
+		argsDiff, err := p.diffBuildArgs(s.BuildArgs, stepBuildArguments(actual))
+		if err != nil {
+			return nil, false, err
+		}
+		if len(argsDiff) > 0 {
+			diff = append(diff, "buildArgs: "+strings.Join(argsDiff, ", "))
+		}
+	}
+	return diff, replace, nil
+}
+
+// baseImageList renders base image dependencies as a sorted, comma separated list.
+func baseImageList(dependencies []containerregistry.BaseImageDependency) string {
+	images := make([]string, len(dependencies))
+	for i, d := range dependencies {
+		images[i] = formatBaseImageDependency(d)
+	}
+	sort.Strings(images)
+	return strings.Join(images, ",")
+}
+
+// diffBuildArgs lists added (+), changed (~) and removed (-) build arguments.
+func (p *planner) diffBuildArgs(wanted []manifest.BuildArg, actual []containerregistry.BuildArgument) ([]string, error) {
+	current := make(map[string]containerregistry.BuildArgument, len(actual))
+	for _, a := range actual {
+		current[to.String(a.Name)] = a
+	}
+
+	var diff []string
+	names := make(map[string]bool, len(wanted))
+	for _, a := range wanted {
+		names[a.Name] = true
+		c, ok := current[a.Name]
+		if !ok {
+			diff = append(diff, "+"+a.Name)
+			continue
+		}
+		if to.Bool(c.IsSecret) != a.Secret {
+			diff = append(diff, "~"+a.Name)
+			continue
+		}
+		if a.Secret {
+			continue
+		}
+		value, err := p.buildArgValue(a)
+		if err != nil {
+			return nil, err
+		}
+		if value != to.String(c.Value) {
+			diff = append(diff, "~"+a.Name)
+		}
+	}
+
+	var removed []string
+	for name := range current {
+		if !names[name] {
+			removed = append(removed, "-"+name)
+		}
+	}
+	sort.Strings(removed)
+	return append(diff, removed...), nil
+}
+
+// buildArgValue reads the value of a build argument from the manifest, a file or the environment.
+func (p *planner) buildArgValue(a manifest.BuildArg) (string, error) {
+	var (
+		value string
+		err   error
+	)
+	switch {
+	case a.ValueFile != "":
+		var path string
+		if path, err = p.path(a.ValueFile); err == nil {
+			value, err = readSecretFile(path, nil)
+		}
+	case a.ValueEnv != "":
+		value, err = readSecretEnv(a.ValueEnv)
+	default:
+		value = a.Value
+	}
+	if err != nil {
+		return "", fmt.Errorf("build argument %s: %v", a.Name, err)
+	}
+	return value, nil
+}
+
+// path expands a leading ~ and resolves relative paths against the manifest's directory.
+func (p *planner) path(name string) (string, error) {
+	path, err := homedir.Expand(name)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) && p.dir != "" {
+		path = filepath.Join(p.dir, path)
+	}
+	return path, nil
+}
+
+// manifestPlatform converts the platform of a manifest entry. A missing cpu count is left to the service.
+func manifestPlatform(p *manifest.Platform) (*containerregistry.PlatformProperties, error) {
+	var cpu int32
+	if p.CPU != nil {
+		if *p.CPU <= 0 {
+			return nil, fmt.Errorf("invalid cpu count %d: it must be a positive number", *p.CPU)
+		}
+		cpu = *p.CPU
+	}
+	return parsePlatform(p.OS, cpu)
+}
+
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.Replace(location, " ", "", -1))
+}
+
+// sameTags compares the tags of a build task to those of a manifest entry,
+// ignoring the tag owned by solstice.
+func sameTags(actual map[string]*string, wanted map[string]string) bool {
+	for k, v := range wanted {
+		if k == tokenRotatedTag {
+			continue
+		}
+		a, ok := actual[k]
+		if !ok || to.String(a) != v {
+			return false
+		}
+	}
+	for k := range actual {
+		if _, ok := wanted[k]; !ok && k != tokenRotatedTag {
+			return false
+		}
+	}
+	return true
+}
+
+// updatesBuildArgs reports whether a step update replaces the build arguments.
+func updatesBuildArgs(diff []string) bool {
+	for _, d := range diff {
+		if strings.HasPrefix(d, "buildArgs:") {
+			return true
+		}
+	}
+	return false
+}
+
+func describeChange(field, current, wanted string) string {
+	return fmt.Sprintf("%s: %s -> %s", field, orDash(current), orDash(wanted))
+}
+
+// printPlanTable renders the changes of a plan as a table, followed by a summary.
+func printPlanTable(out io.Writer, actions []view.PlanAction) error {
+	if len(actions) == 0 {
+		_, err := fmt.Fprintln(out, "No changes. The registry matches the manifest.")
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tKIND\tTASK\tSTEP\tCHANGES")
+	counts := make(map[string]int)
+	for _, a := range actions {
+		counts[a.Action]++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", a.Action, a.Kind, a.BuildTask, orDash(a.Step), orDash(strings.Join(a.Changes, "; ")))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "\nPlan: %d to create, %d to update, %d to replace, %d to delete.\n",
+		counts[planCreate], counts[planUpdate], counts[planReplace], counts[planDelete])
+	return err
+}
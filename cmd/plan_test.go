@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/pkg/manifest"
+)
+
+const planTestManifest = `
+apiVersion: solstice/v1
+buildTasks:
+- name: webapp
+  location: westus
+  source:
+    type: Github
+    repositoryUrl: https://github.com/org/webapp
+    tokenEnv: WEBAPP_TOKEN
+  steps:
+  - name: webappstep
+    image: webapp:v1
+    buildArgs:
+    - name: VERSION
+      value: "2"
+`
+
+func parsePlanManifest(t *testing.T, data string) *manifest.Manifest {
+	t.Helper()
+	m, err := manifest.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("failed to parse the manifest: %v", err)
+	}
+	return m
+}
+
+func planTestTask(name string, tags map[string]*string) containerregistry.BuildTask {
+	return containerregistry.BuildTask{
+		Name:     to.StringPtr(name),
+		Location: to.StringPtr("West US"),
+		Tags:     tags,
+		BuildTaskProperties: &containerregistry.BuildTaskProperties{
+			Alias:  to.StringPtr(name),
+			Status: containerregistry.Enabled,
+			SourceRepository: &containerregistry.SourceRepositoryProperties{
+				SourceControlType:      containerregistry.Github,
+				RepositoryURL:          to.StringPtr("https://github.com/org/webapp.git"),
+				IsCommitTriggerEnabled: to.BoolPtr(true),
+			},
+			Platform: &containerregistry.PlatformProperties{OsType: containerregistry.Linux, CPU: to.Int32Ptr(2)},
+			Timeout:  to.Int32Ptr(3600),
+		},
+	}
+}
+
+func planTestStep(name string, args ...containerregistry.BuildArgument) containerregistry.BuildStep {
+	return containerregistry.BuildStep{
+		Name: to.StringPtr(name),
+		BasicBuildStepProperties: containerregistry.DockerBuildStep{
+			Branch:           to.StringPtr("master"),
+			ImageName:        to.StringPtr("webapp:v1"),
+			IsPushEnabled:    to.BoolPtr(true),
+			DockerFilePath:   to.StringPtr("Dockerfile"),
+			ContextPath:      to.StringPtr("."),
+			BuildArguments:   &args,
+			BaseImageTrigger: containerregistry.Runtime,
+		},
+	}
+}
+
+func newPlanTestState(tasks []containerregistry.BuildTask, steps map[string][]containerregistry.BuildStep) *registryState {
+	state := &registryState{
+		tasks: map[string]containerregistry.BuildTask{},
+		steps: map[string]map[string]containerregistry.BuildStep{},
+	}
+	for _, t := range tasks {
+		key := strings.ToLower(to.String(t.Name))
+		state.tasks[key] = t
+		state.steps[key] = map[string]containerregistry.BuildStep{}
+		for _, s := range steps[key] {
+			state.steps[key][strings.ToLower(to.String(s.Name))] = s
+		}
+	}
+	return state
+}
+
+// planSummary renders the changes of a plan as "action kind task[/step]: changes".
+func planSummary(changes []plannedChange) []string {
+	var summary []string
+	for _, c := range changes {
+		s := c.Action + " " + c.Kind + " " + c.BuildTask
+		if c.Step != "" {
+			s += "/" + c.Step
+		}
+		if len(c.Changes) > 0 {
+			s += ": " + strings.Join(c.Changes, "; ")
+		}
+		summary = append(summary, s)
+	}
+	return summary
+}
+
+func TestPlan(t *testing.T) {
+	version := containerregistry.BuildArgument{Name: to.StringPtr("VERSION"), Value: to.StringPtr("2"), IsSecret: to.BoolPtr(false)}
+	oldVersion := containerregistry.BuildArgument{Name: to.StringPtr("VERSION"), Value: to.StringPtr("1"), IsSecret: to.BoolPtr(false)}
+	secret := containerregistry.BuildArgument{Name: to.StringPtr("PASSWORD"), Value: to.StringPtr("*****"), IsSecret: to.BoolPtr(true)}
+
+	tests := []struct {
+		name     string
+		manifest string
+		planner  planner
+		tasks    []containerregistry.BuildTask
+		steps    map[string][]containerregistry.BuildStep
+		want     []string
+	}{
+		{
+			name:     "create",
+			manifest: planTestManifest,
+			want: []string{
+				"create BuildTask webapp",
+				"create BuildStep webapp/webappstep",
+			},
+		},
+		{
+			name:     "up to date",
+			manifest: planTestManifest,
+			tasks:    []containerregistry.BuildTask{planTestTask("webapp", nil)},
+			steps:    map[string][]containerregistry.BuildStep{"webapp": {planTestStep("webappstep", version)}},
+		},
+		{
+			name:     "unmanaged fields are left alone",
+			manifest: planTestManifest,
+			planner:  planner{},
+			tasks: []containerregistry.BuildTask{planTestTask("webapp", map[string]*string{
+				"team":          to.StringPtr("web"),
+				tokenRotatedTag: to.StringPtr("2018-01-01T00:00:00Z"),
+			})},
+			steps: map[string][]containerregistry.BuildStep{"webapp": {planTestStep("webappstep", version)}},
+		},
+		{
+			name: "update",
+			manifest: planTestManifest + `
+  status: Disabled
+  platform:
+    os: windows
+  timeout: 7200
+  tags:
+    team: web
+`,
+			tasks: []containerregistry.BuildTask{planTestTask("webapp", map[string]*string{
+				tokenRotatedTag: to.StringPtr("2018-01-01T00:00:00Z"),
+			})},
+			steps: map[string][]containerregistry.BuildStep{"webapp": {planTestStep("webappstep", oldVersion, secret)}},
+			want: []string{
+				"update BuildTask webapp: status: Enabled -> Disabled; platform.os: Linux -> Windows; timeout: 3600 -> 7200; tags",
+				"update BuildStep webapp/webappstep: buildArgs: ~VERSION, -PASSWORD",
+			},
+		},
+		{
+			name:     "replace task",
+			manifest: strings.Replace(planTestManifest, "github.com/org/webapp", "github.com/org/other", 1),
+			tasks:    []containerregistry.BuildTask{planTestTask("webapp", nil)},
+			steps:    map[string][]containerregistry.BuildStep{"webapp": {planTestStep("webappstep", version)}},
+			want: []string{
+				"replace BuildTask webapp: source.repositoryUrl: https://github.com/org/webapp.git -> https://github.com/org/other",
+				"create BuildStep webapp/webappstep",
+			},
+		},
+		{
+			name:     "replace step",
+			manifest: planTestManifest,
+			tasks:    []containerregistry.BuildTask{planTestTask("webapp", nil)},
+			steps: map[string][]containerregistry.BuildStep{"webapp": {{
+				Name:                     to.StringPtr("webappstep"),
+				BasicBuildStepProperties: containerregistry.BuildStepProperties{},
+			}}},
+			want: []string{
+				"replace BuildStep webapp/webappstep: type: - -> Docker",
+			},
+		},
+		{
+			name:     "replace step for base images",
+			manifest: planTestManifest + "    baseImages:\n    - node:8\n",
+			tasks:    []containerregistry.BuildTask{planTestTask("webapp", nil)},
+			steps:    map[string][]containerregistry.BuildStep{"webapp": {planTestStep("webappstep", version)}},
+			want: []string{
+				"replace BuildStep webapp/webappstep: baseImages: - -> BuildTime=node:8",
+			},
+		},
+		{
+			name:     "without prune",
+			manifest: planTestManifest,
+			tasks:    []containerregistry.BuildTask{planTestTask("webapp", nil), planTestTask("legacy", nil)},
+			steps: map[string][]containerregistry.BuildStep{"webapp": {
+				planTestStep("webappstep", version),
+				planTestStep("extrastep"),
+			}},
+		},
+		{
+			name:     "prune",
+			manifest: planTestManifest,
+			planner:  planner{prune: true},
+			tasks:    []containerregistry.BuildTask{planTestTask("webapp", nil), planTestTask("legacy", nil)},
+			steps: map[string][]containerregistry.BuildStep{"webapp": {
+				planTestStep("webappstep", version),
+				planTestStep("extrastep"),
+			}},
+			want: []string{
+				"delete BuildStep webapp/extrastep",
+				"delete BuildTask legacy",
+			},
+		},
+		{
+			name:     "create only",
+			manifest: strings.Replace(planTestManifest, "github.com/org/webapp", "github.com/org/other", 1),
+			planner:  planner{createOnly: true, prune: true},
+			tasks:    []containerregistry.BuildTask{planTestTask("webapp", nil)},
+			want: []string{
+				"create BuildStep webapp/webappstep",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := parsePlanManifest(t, tt.manifest)
+			changes, err := tt.planner.plan(m, newPlanTestState(tt.tasks, tt.steps))
+			if err != nil {
+				t.Fatalf("plan errored: %v", err)
+			}
+			if got := planSummary(changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plan =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestPlanCreateNeedsToken(t *testing.T) {
+	m := parsePlanManifest(t, strings.Replace(planTestManifest, "tokenEnv: WEBAPP_TOKEN", "", 1))
+	p := planner{}
+	if _, err := p.plan(m, newPlanTestState(nil, nil)); err == nil {
+		t.Error("planning the creation of a build task without a token source didn't error")
+	}
+	if _, err := p.plan(m, newPlanTestState([]containerregistry.BuildTask{planTestTask("webapp", nil)}, nil)); err != nil {
+		t.Errorf("planning the update of a build task without a token source errored: %v", err)
+	}
+}
+
+func TestDiffBuildArgs(t *testing.T) {
+	actual := []containerregistry.BuildArgument{
+		{Name: to.StringPtr("SAME"), Value: to.StringPtr("a"), IsSecret: to.BoolPtr(false)},
+		{Name: to.StringPtr("CHANGED"), Value: to.StringPtr("a"), IsSecret: to.BoolPtr(false)},
+		{Name: to.StringPtr("SECRET"), Value: to.StringPtr("*****"), IsSecret: to.BoolPtr(true)},
+		{Name: to.StringPtr("NOWSECRET"), Value: to.StringPtr("a"), IsSecret: to.BoolPtr(false)},
+		{Name: to.StringPtr("REMOVED"), Value: to.StringPtr("a"), IsSecret: to.BoolPtr(false)},
+	}
+	wanted := []manifest.BuildArg{
+		{Name: "SAME", Value: "a"},
+		{Name: "CHANGED", Value: "b"},
+		// The values of secrets aren't compared, nor read.
+		{Name: "SECRET", Secret: true, ValueEnv: "SOLSTICE_TEST_UNSET_VARIABLE"},
+		{Name: "NOWSECRET", Secret: true, ValueEnv: "SOLSTICE_TEST_UNSET_VARIABLE"},
+		{Name: "ADDED", Value: "a"},
+	}
+	p := planner{}
+	got, err := p.diffBuildArgs(wanted, actual)
+	if err != nil {
+		t.Fatalf("diffBuildArgs errored: %v", err)
+	}
+	want := []string{"~CHANGED", "~NOWSECRET", "+ADDED", "-REMOVED"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffBuildArgs = %q, want %q", got, want)
+	}
+}
@@ -3,19 +3,55 @@ package cmd
 import (
 	"os"
 
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/iam"
 	"github.com/spf13/cobra"
 )
 
 const globalUsageMessage = `Solstice.
 
 To start working with solstice, run 'solstice help'.
-`
+
+Results are written to stdout in the format selected with --output, while
+progress messages are written to stderr.
+
+Authentication is selected with --auth-mode (or AZURE_AUTH_MODE):
+  login        The credentials cached by 'solstice login'.
+  cli          The tokens cached by 'az login'.
+  device       Device flow, also used when AZURE_AUTH_DEVICEFLOW is set.
+  secret       A service principal with a client secret, from --tenant-id,
+               --client-id and --client-secret-file, or AZURE_TENANT_ID,
+               AZURE_CLIENT_ID and AZURE_CLIENT_SECRET.
+  certificate  A service principal with a PEM or PFX certificate, from
+               --certificate-file or AZURE_CERTIFICATE_PATH, and the password
+               of PFX files from --certificate-password-file or
+               AZURE_CERTIFICATE_PASSWORD.
+  msi          The managed identity of the Azure VM, or the user assigned
+               identity given with --client-id. --msi-endpoint (or
+               MSI_ENDPOINT) replaces the VM's metadata service.
+  auto         The default: a service principal whenever a client ID is
+               given, using a certificate if one is given; device flow if
+               AZURE_AUTH_DEVICEFLOW is set; 'solstice login' if it was
+               used for the cloud; and 'az login' otherwise.
+Flags take precedence over environment variables.
+
+The cloud is selected with --cloud (or AZURE_ENVIRONMENT, AZURE_ARM_ENDPOINT,
+or "cloud" in ~/.solstice/config.json): public, china, usgovernment, german,
+the path of an environment JSON file, or the URL of an Azure Stack Resource
+Manager endpoint. Every endpoint and token audience is taken from it.
+
+` + exitCodesMessage
+
+// offlineAnnotation marks the commands which never talk to Azure. Neither the
+// cloud nor the credentials are resolved before they run, so that a cloud given
+// as a URL isn't fetched and an invalid one doesn't make them fail.
+const offlineAnnotation = "solstice_offline"
 
 // Execute executes the root command.
 func Execute() {
 	cmd := newRootCmd(os.Args[1:])
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -25,9 +61,34 @@ func newRootCmd(args []string) *cobra.Command {
 		Short:        "A CLI for ACR Build.",
 		Long:         globalUsageMessage,
 		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := cmd.Annotations[offlineAnnotation]; ok {
+				return nil
+			}
+			if settings.debug {
+				client.EnableTracing(os.Stderr)
+			}
+			env, err := resolveEnvironment(settings.cloud)
+			if err != nil {
+				return validationError(err)
+			}
+			iam.SetEnvironment(env)
+			if err = checkStdinFlags(cmd); err != nil {
+				return validationError(err)
+			}
+			if err = iam.Init(settings.credentials(os.Stdin)); err != nil {
+				return validationError(err)
+			}
+			return nil
+		},
 	}
 
 	flags := cmd.PersistentFlags()
+	settings.addFlags(flags)
+
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return validationError(err)
+	})
 
 	out := cmd.OutOrStdout()
 
@@ -36,9 +97,33 @@ func newRootCmd(args []string) *cobra.Command {
 		newBuildCmd(out),
 		newListCmd(out),
 		newLogsCmd(out),
+		newCancelCmd(out),
+		newShowCmd(out),
+		newTaskCmd(out),
+		newStepCmd(out),
+		newApplyCmd(out),
+		newLoginCmd(out),
+		newLogoutCmd(out),
+		newWhoamiCmd(out),
 	)
 
+	// The help command is only created on demand, so create it to mark it.
+	cmd.InitDefaultHelpCmd()
+	for _, c := range cmd.Commands() {
+		if c.Name() == "help" {
+			markOffline(c)
+		}
+	}
+
 	flags.Parse(args)
 
 	return cmd
 }
+
+// markOffline marks a command which never talks to Azure.
+func markOffline(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[offlineAnnotation] = "true"
+}
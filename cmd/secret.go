@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Secrets such as tokens and secret build argument values are read from files,
+// stdin or the environment rather than taken as flag values, so that they don't
+// end up in the shell history or the process list. Errors never include them.
+
+// readSecretFile reads a secret from a file, or from in if path is "-".
+// Surrounding whitespace, such as a trailing newline, is removed.
+func readSecretFile(path string, in io.Reader) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if path == "-" {
+		b, err = ioutil.ReadAll(in)
+	} else {
+		b, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the secret: %v", err)
+	}
+
+	secret := strings.TrimSpace(string(b))
+	if secret == "" {
+		if path == "-" {
+			return "", errors.New("the secret read from stdin is empty")
+		}
+		return "", fmt.Errorf("the secret read from %s is empty", path)
+	}
+	return secret, nil
+}
+
+// readSecretEnv reads a secret from an environment variable.
+func readSecretEnv(name string) (string, error) {
+	secret, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("the environment variable %s is not set", name)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("the environment variable %s is empty", name)
+	}
+	return secret, nil
+}
+
+// stdinAnnotation marks the flags naming a file which is read from stdin when it's '-'.
+const stdinAnnotation = "solstice_stdin"
+
+// markStdinFlags records that the given flags read stdin when they're '-'.
+func markStdinFlags(fs *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		if err := fs.SetAnnotation(name, stdinAnnotation, []string{"true"}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// checkStdinFlags makes sure that at most one of the flags of a command reads
+// stdin: the first one to read it would leave nothing for the others.
+func checkStdinFlags(cmd *cobra.Command) error {
+	var names []string
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[stdinAnnotation]; ok && f.Changed && f.Value.String() == "-" {
+			names = append(names, "--"+f.Name)
+		}
+	})
+	if len(names) > 1 {
+		sort.Strings(names)
+		last := len(names) - 1
+		return fmt.Errorf("only one of %s and %s can be read from stdin", strings.Join(names[:last], ", "), names[last])
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ehotinger/solstice/iam"
+	"github.com/ehotinger/solstice/pkg/printer"
+	"github.com/spf13/pflag"
+)
+
+// globalSettings holds the values of the persistent flags shared by every command.
+type globalSettings struct {
+	output                  string
+	debug                   bool
+	cloud                   string
+	subscription            string
+	authMode                string
+	tenantID                string
+	clientID                string
+	clientSecretFile        string
+	certificateFile         string
+	certificatePasswordFile string
+	msiEndpoint             string
+}
+
+// settings is populated by the root command's persistent flags.
+var settings globalSettings
+
+func (s *globalSettings) addFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&s.output, "output", "o", string(printer.FormatTable), "The output format: "+printer.Formats)
+	fs.BoolVar(&s.debug, "debug", false, "Write a trace of every HTTP request to stderr, with credentials and secrets redacted")
+	fs.StringVar(&s.cloud, "cloud", "", "The Azure cloud: "+iam.Clouds+" (overrides AZURE_ENVIRONMENT, AZURE_ARM_ENDPOINT and the config file)")
+	fs.StringVar(&s.subscription, "subscription", "", "The ID or name of the subscription to use (overrides AZURE_SUBSCRIPTION_ID and the config file, defaults to the az CLI's default subscription)")
+	fs.StringVar(&s.authMode, "auth-mode", "", "How to authenticate: "+iam.AuthModes+" (overrides AZURE_AUTH_MODE, defaults to auto)")
+	fs.StringVar(&s.tenantID, "tenant-id", "", "The tenant of the service principal to authenticate as (overrides AZURE_TENANT_ID)")
+	fs.StringVar(&s.clientID, "client-id", "", "The client ID of the service principal, or of the user assigned managed identity, to authenticate as (overrides AZURE_CLIENT_ID)")
+	fs.StringVar(&s.clientSecretFile, "client-secret-file", "", "A file containing the service principal's client secret, or '-' to read it from stdin (overrides AZURE_CLIENT_SECRET)")
+	fs.StringVar(&s.certificateFile, "certificate-file", "", "A PEM or PFX file containing the service principal's certificate and private key (overrides AZURE_CERTIFICATE_PATH)")
+	fs.StringVar(&s.certificatePasswordFile, "certificate-password-file", "", "A file containing the password of a PFX certificate, or '-' to read it from stdin (overrides AZURE_CERTIFICATE_PASSWORD)")
+	fs.StringVar(&s.msiEndpoint, "msi-endpoint", "", "The managed identity endpoint to get tokens from (overrides MSI_ENDPOINT, defaults to the VM's metadata service)")
+	markStdinFlags(fs, "client-secret-file", "certificate-password-file")
+}
+
+// credentials returns the credentials given with flags. The client secret and
+// the certificate password are only read from their files once they're needed.
+func (s *globalSettings) credentials(in io.Reader) iam.Credentials {
+	creds := iam.Credentials{
+		AuthMode:        s.authMode,
+		TenantID:        s.tenantID,
+		ClientID:        s.clientID,
+		CertificatePath: s.certificateFile,
+		MSIEndpoint:     s.msiEndpoint,
+	}
+	if path := s.clientSecretFile; path != "" {
+		creds.ReadClientSecret = func() (string, error) {
+			secret, err := readSecretFile(path, in)
+			if err != nil {
+				return "", fmt.Errorf("failed to read the client secret: %v", err)
+			}
+			return secret, nil
+		}
+	}
+	if path := s.certificatePasswordFile; path != "" {
+		creds.ReadCertificatePassword = func() (string, error) {
+			password, err := readSecretFile(path, in)
+			if err != nil {
+				return "", fmt.Errorf("failed to read the certificate password: %v", err)
+			}
+			return password, nil
+		}
+	}
+	return creds
+}
+
+// newPrinter creates the printer selected with --output.
+func (s *globalSettings) newPrinter() (*printer.Printer, error) {
+	p, err := printer.New(s.output)
+	if err != nil {
+		return nil, validationError(err)
+	}
+	return p, nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+type showCmd struct {
+	resourceGroupName string
+	registryName      string
+	buildID           string
+	out               io.Writer
+}
+
+func newShowCmd(out io.Writer) *cobra.Command {
+	showCmd := &showCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "show BUILD_ID",
+		Short: "Show the details of a build",
+		Long:  "Show the details of a build",
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			showCmd.buildID = args[0]
+			return showCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&showCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&showCmd.registryName, "n", "", "The name of the registry")
+
+	return cmd
+}
+
+func (c *showCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	bc, err := client.GetBuildsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	build, err := bc.Get(ctx, c.resourceGroupName, c.registryName, c.buildID)
+	if err != nil {
+		return wrapAPIError("Errored while getting the build", err)
+	}
+
+	v := view.NewBuild(build)
+	return p.Print(c.out, v, func(w io.Writer) error {
+		return printBuildDetails(w, v)
+	})
+}
+
+// printBuildDetails renders a single build as a list of fields followed by its output images.
+func printBuildDetails(out io.Writer, b view.Build) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Build ID:\t%s\n", b.ID)
+	fmt.Fprintf(w, "Status:\t%s\n", orDash(b.Status))
+	fmt.Fprintf(w, "Build Type:\t%s\n", orDash(b.BuildType))
+	fmt.Fprintf(w, "Trigger:\t%s\n", orDash(b.Trigger))
+	fmt.Fprintf(w, "Build Task:\t%s\n", orDash(b.BuildTask))
+	fmt.Fprintf(w, "Platform:\t%s\n", formatPlatform(b.Platform))
+	fmt.Fprintf(w, "Created:\t%s\n", formatTime(b.CreateTime))
+	fmt.Fprintf(w, "Started:\t%s\n", formatTime(b.StartTime))
+	fmt.Fprintf(w, "Finished:\t%s\n", formatTime(b.FinishTime))
+	fmt.Fprintf(w, "Queue Wait:\t%s\n", formatDuration(b.QueueWait()))
+	fmt.Fprintf(w, "Run Duration:\t%s\n", formatDuration(b.RunDuration()))
+	fmt.Fprintf(w, "Archive Enabled:\t%t\n", b.IsArchiveEnabled)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	if len(b.OutputImages) == 0 {
+		fmt.Fprintln(out, "Output Images: none")
+		return nil
+	}
+	fmt.Fprintln(out, "Output Images:")
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "  REPOSITORY\tTAG\tDIGEST")
+	for _, img := range b.OutputImages {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", img.Repository, orDash(img.Tag), orDash(img.Digest))
+	}
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func formatPlatform(p *view.Platform) string {
+	if p == nil {
+		return "-"
+	}
+	if p.CPU == nil {
+		return orDash(p.OsType)
+	}
+	return fmt.Sprintf("%s (%d CPU)", orDash(p.OsType), *p.CPU)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatDuration(d time.Duration, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return d.String()
+}
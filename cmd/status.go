@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+)
+
+// isTerminalStatus reports whether a build with the given status has finished running.
+func isTerminalStatus(status containerregistry.BuildStatus) bool {
+	switch status {
+	case containerregistry.Succeeded,
+		containerregistry.Failed,
+		containerregistry.Canceled,
+		containerregistry.Timeout,
+		containerregistry.AbandonedAsSystemError:
+		return true
+	}
+	return false
+}
+
+// parseBuildStatus converts a case insensitive status name into a BuildStatus.
+func parseBuildStatus(s string) (containerregistry.BuildStatus, error) {
+	var names []string
+	for _, status := range containerregistry.PossibleBuildStatusValues() {
+		if strings.EqualFold(s, string(status)) {
+			return status, nil
+		}
+		names = append(names, string(status))
+	}
+	return "", fmt.Errorf("invalid build status %q: it must be one of %s", s, strings.Join(names, ", "))
+}
+
+// parseBuildType converts a case insensitive build type name into a BuildType.
+func parseBuildType(s string) (containerregistry.BuildType, error) {
+	var names []string
+	for _, t := range containerregistry.PossibleBuildTypeValues() {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+		names = append(names, string(t))
+	}
+	return "", fmt.Errorf("invalid build type %q: it must be one of %s", s, strings.Join(names, ", "))
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/pkg/printer"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const stepLongMessage = `
+Manage the steps of a build task.
+
+Each step builds a Docker image from a branch of the build task's source
+repository. Every step command needs the build task to be given with --task.
+`
+
+func newStepCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "step",
+		Short: "Manage the steps of build tasks",
+		Long:  stepLongMessage,
+	}
+
+	cmd.AddCommand(
+		newStepCreateCmd(out),
+		newStepListCmd(out),
+		newStepShowCmd(out),
+		newStepUpdateCmd(out),
+		newStepDeleteCmd(out),
+		newStepArgsCmd(out),
+	)
+
+	return cmd
+}
+
+// validateStepTarget checks the flags identifying the build task of a step command.
+func validateStepTarget(resourceGroupName, registryName, task string) error {
+	if err := validateRegistry(resourceGroupName, registryName); err != nil {
+		return err
+	}
+	if task == "" {
+		return errors.New("a build task must be specified with --task")
+	}
+	return nil
+}
+
+// parseBaseImageTrigger converts a case insensitive trigger name into a BaseImageTriggerType.
+func parseBaseImageTrigger(s string) (containerregistry.BaseImageTriggerType, error) {
+	var names []string
+	for _, t := range containerregistry.PossibleBaseImageTriggerTypeValues() {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+		names = append(names, string(t))
+	}
+	return "", fmt.Errorf("invalid base image trigger %q: it must be one of %s", s, strings.Join(names, ", "))
+}
+
+// parseBaseImageDependencies converts [TYPE=]REPOSITORY[:TAG] values into base image dependencies.
+// TYPE is BuildTime or RunTime and defaults to BuildTime. Updates of the images trigger a
+// build unless autoTrigger is false.
+func parseBaseImageDependencies(values []string, autoTrigger bool) ([]containerregistry.BaseImageDependency, error) {
+	var result []containerregistry.BaseImageDependency
+	for _, value := range values {
+		depType := containerregistry.BuildTime
+		image := value
+		if i := strings.Index(value, "="); i >= 0 {
+			var err error
+			if depType, err = parseBaseImageDependencyType(value[:i]); err != nil {
+				return nil, err
+			}
+			image = value[i+1:]
+		}
+		if err := validateImageName(image); err != nil {
+			return nil, err
+		}
+
+		dep := containerregistry.BaseImageDependency{
+			Type:                 depType,
+			IsAutoTriggerEnabled: to.BoolPtr(autoTrigger),
+		}
+		// The image name has been validated, so a colon can only separate the tag.
+		if i := strings.LastIndex(image, ":"); i >= 0 {
+			dep.RepositoryName = to.StringPtr(image[:i])
+			dep.Tag = to.StringPtr(image[i+1:])
+		} else {
+			dep.RepositoryName = to.StringPtr(image)
+		}
+		result = append(result, dep)
+	}
+	return result, nil
+}
+
+// formatBaseImageDependency renders a base image dependency in the
+// TYPE=REPOSITORY[:TAG] form parsed by parseBaseImageDependencies.
+func formatBaseImageDependency(d containerregistry.BaseImageDependency) string {
+	image := to.String(d.RepositoryName)
+	if d.Tag != nil && *d.Tag != "" {
+		image += ":" + *d.Tag
+	}
+	return string(d.Type) + "=" + image
+}
+
+func parseBaseImageDependencyType(s string) (containerregistry.BaseImageDependencyType, error) {
+	var names []string
+	for _, t := range containerregistry.PossibleBaseImageDependencyTypeValues() {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+		names = append(names, string(t))
+	}
+	return "", fmt.Errorf("invalid base image dependency type %q: it must be one of %s", s, strings.Join(names, ", "))
+}
+
+// printStep writes a build step to out using the printer's format.
+func printStep(p *printer.Printer, out io.Writer, step containerregistry.BuildStep) error {
+	v := view.NewBuildStep(step)
+	return p.Print(out, v, func(w io.Writer) error {
+		return printStepDetails(w, v)
+	})
+}
+
+// printStepDetails renders a single build step as a list of fields, followed by
+// its build arguments and base image dependencies.
+func printStepDetails(out io.Writer, s view.BuildStep) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", s.Name)
+	fmt.Fprintf(w, "Type:\t%s\n", orDash(s.Type))
+	fmt.Fprintf(w, "Provisioning State:\t%s\n", orDash(s.ProvisioningState))
+	fmt.Fprintf(w, "Branch:\t%s\n", orDash(s.Branch))
+	fmt.Fprintf(w, "Image:\t%s\n", orDash(s.ImageName))
+	fmt.Fprintf(w, "Push Enabled:\t%t\n", s.IsPushEnabled)
+	fmt.Fprintf(w, "Dockerfile:\t%s\n", orDash(s.DockerFilePath))
+	fmt.Fprintf(w, "Context:\t%s\n", orDash(s.ContextPath))
+	fmt.Fprintf(w, "Base Image Trigger:\t%s\n", orDash(s.BaseImageTrigger))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	if len(s.BuildArguments) == 0 {
+		fmt.Fprintln(out, "Build Arguments: none")
+	} else {
+		fmt.Fprintln(out, "Build Arguments:")
+		if err := printBuildArgumentTable(out, s.BuildArguments, "  "); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(out)
+	if len(s.BaseImageDependencies) == 0 {
+		fmt.Fprintln(out, "Base Image Dependencies: none")
+		return nil
+	}
+	fmt.Fprintln(out, "Base Image Dependencies:")
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tREPOSITORY\tTAG\tDIGEST\tAUTO TRIGGER")
+	for _, d := range s.BaseImageDependencies {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%t\n", orDash(d.Type), orDash(d.Repository), orDash(d.Tag), orDash(d.Digest), d.IsAutoTriggerEnabled)
+	}
+	return w.Flush()
+}
+
+// printBuildArgumentTable renders build arguments as a table, each line starting with indent.
+// Secret values have already been masked by the view.
+func printBuildArgumentTable(out io.Writer, args []view.BuildArgument, indent string) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "%sNAME\tVALUE\tSECRET\n", indent)
+	for _, a := range args {
+		fmt.Fprintf(w, "%s%s\t%s\t%t\n", indent, a.Name, a.Value, a.IsSecret)
+	}
+	return w.Flush()
+}
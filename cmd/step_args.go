@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/pkg/printer"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const stepArgsLongMessage = `
+Manage the build arguments of a build step.
+
+The values of secret build arguments are masked whenever they are shown, and
+are read from a file, stdin or an environment variable instead of the command
+line when they are set.
+`
+
+func newStepArgsCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "args",
+		Short: "Manage the build arguments of a build step",
+		Long:  stepArgsLongMessage,
+	}
+
+	cmd.AddCommand(
+		newStepArgsListCmd(out),
+		newStepArgsSetCmd(out),
+		newStepArgsUnsetCmd(out),
+	)
+
+	return cmd
+}
+
+// listBuildArguments returns every build argument of a build step, including the values of secrets.
+func listBuildArguments(ctx context.Context, sc containerregistry.BuildStepsClient, resourceGroupName, registryName, task, step string) ([]containerregistry.BuildArgument, error) {
+	iter, err := sc.ListBuildArgumentsComplete(ctx, resourceGroupName, registryName, task, step)
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing build arguments", err)
+	}
+
+	var args []containerregistry.BuildArgument
+	for ; iter.NotDone(); err = iter.Next() {
+		if err != nil {
+			return nil, wrapAPIError("Errored while listing build arguments", err)
+		}
+		args = append(args, iter.Value())
+	}
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing build arguments", err)
+	}
+	return args, nil
+}
+
+// updateBuildArguments replaces every build argument of a build step.
+func updateBuildArguments(ctx context.Context, sc containerregistry.BuildStepsClient, resourceGroupName, registryName, task, step string, args []containerregistry.BuildArgument) (containerregistry.BuildStep, error) {
+	// An empty list, rather than null, clears the step's build arguments.
+	args = append([]containerregistry.BuildArgument{}, args...)
+	params := containerregistry.BuildStepUpdateParameters{
+		BasicBuildStepPropertiesUpdateParameters: containerregistry.DockerBuildStepUpdateParameters{
+			BuildArguments: &args,
+		},
+	}
+
+	future, err := sc.Update(ctx, resourceGroupName, registryName, task, step, params)
+	if err != nil {
+		return containerregistry.BuildStep{}, wrapAPIError("Errored while updating the build arguments", err)
+	}
+	if err = future.WaitForCompletion(ctx, sc.Client); err != nil {
+		return containerregistry.BuildStep{}, wrapAPIError("Errored while waiting for the build arguments to be updated", err)
+	}
+	updated, err := future.Result(sc)
+	if err != nil {
+		return updated, wrapAPIError("Errored while getting the updated build step", err)
+	}
+	return updated, nil
+}
+
+// checkSecretsPreserved makes sure that every secret build argument which is
+// sent back to the service still has its value. Arguments named in replaced
+// are being given new values and don't need one.
+func checkSecretsPreserved(args []containerregistry.BuildArgument, replaced map[string]bool) error {
+	var lost []string
+	for _, a := range args {
+		name := to.String(a.Name)
+		if a.IsSecret != nil && *a.IsSecret && a.Value == nil && !replaced[name] {
+			lost = append(lost, name)
+		}
+	}
+	if len(lost) > 0 {
+		sort.Strings(lost)
+		return fmt.Errorf("the values of the secret build argument(s) %s couldn't be read back and would be lost; set them again or unset them in the same command", strings.Join(lost, ", "))
+	}
+	return nil
+}
+
+// printBuildArguments writes build arguments to out using the printer's format.
+// Secret values are masked.
+func printBuildArguments(p *printer.Printer, out io.Writer, args []containerregistry.BuildArgument) error {
+	list := view.BuildArgumentList{Items: view.NewBuildArguments(args)}
+	sort.SliceStable(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	return p.Print(out, list, func(w io.Writer) error {
+		if len(list.Items) == 0 {
+			_, err := fmt.Fprintln(w, "No build arguments.")
+			return err
+		}
+		return printBuildArgumentTable(w, list.Items, "")
+	})
+}
+
+// stepBuildArguments returns the build arguments of a Docker build step.
+func stepBuildArguments(step containerregistry.BuildStep) []containerregistry.BuildArgument {
+	if step.BasicBuildStepProperties == nil {
+		return nil
+	}
+	d, ok := step.BasicBuildStepProperties.AsDockerBuildStep()
+	if !ok || d.BuildArguments == nil {
+		return nil
+	}
+	return *d.BuildArguments
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+type stepArgsListCmd struct {
+	resourceGroupName string
+	registryName      string
+	task              string
+	step              string
+	out               io.Writer
+}
+
+func newStepArgsListCmd(out io.Writer) *cobra.Command {
+	listCmd := &stepArgsListCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "list STEP",
+		Short: "List the build arguments of a build step",
+		Long:  "List the build arguments of a build step, sorted by name. The values of secret build arguments are masked.",
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listCmd.step = args[0]
+			return listCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&listCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&listCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&listCmd.task, "task", "", "The name of the build task")
+
+	return cmd
+}
+
+func (c *stepArgsListCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateStepTarget(c.resourceGroupName, c.registryName, c.task); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	args, err := listBuildArguments(ctx, sc, c.resourceGroupName, c.registryName, c.task, c.step)
+	if err != nil {
+		return err
+	}
+
+	return printBuildArguments(p, c.out, args)
+}
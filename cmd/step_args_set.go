@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+const stepArgsSetLongMessage = `
+Add build arguments to a build step, or change their values.
+
+Plain build arguments are given as NAME=VALUE. A single argument can instead
+take its value from a file (--value-file, or '-' for stdin) or from an
+environment variable (--value-env). Secret build arguments (--secret) must be
+set this way, so that their values never appear on the command line.
+
+Examples:
+  solstice step args set --rg mygroup --n myregistry --task mytask mystep VERSION=1.0 CHANNEL=beta
+  solstice step args set --rg mygroup --n myregistry --task mytask mystep NPM_TOKEN --secret --value-env NPM_TOKEN
+  vault read -field=token secret/npm | solstice step args set --rg mygroup --n myregistry \
+    --task mytask mystep NPM_TOKEN --secret --value-file -
+`
+
+type stepArgsSetCmd struct {
+	resourceGroupName string
+	registryName      string
+	task              string
+	step              string
+	args              []string
+	secret            bool
+	valueFile         string
+	valueEnv          string
+	in                io.Reader
+	out               io.Writer
+}
+
+func newStepArgsSetCmd(out io.Writer) *cobra.Command {
+	setCmd := &stepArgsSetCmd{
+		in:  os.Stdin,
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "set STEP NAME[=VALUE]...",
+		Short: "Set build arguments of a build step",
+		Long:  stepArgsSetLongMessage,
+		Args:  args(cobra.MinimumNArgs(2)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			setCmd.step = args[0]
+			setCmd.args = args[1:]
+			return setCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&setCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&setCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&setCmd.task, "task", "", "The name of the build task")
+	f.BoolVar(&setCmd.secret, "secret", false, "Set a secret build argument")
+	f.StringVar(&setCmd.valueFile, "value-file", "", "Read the value from this file, or from stdin if it's '-'")
+	f.StringVar(&setCmd.valueEnv, "value-env", "", "Read the value from this environment variable")
+	markStdinFlags(f, "value-file")
+
+	return cmd
+}
+
+func (c *stepArgsSetCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	newArgs, err := c.parseArgs()
+	if err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	current, err := listBuildArguments(ctx, sc, c.resourceGroupName, c.registryName, c.task, c.step)
+	if err != nil {
+		return err
+	}
+
+	replaced := make(map[string]bool, len(newArgs))
+	for _, a := range newArgs {
+		replaced[*a.Name] = true
+	}
+	if err = checkSecretsPreserved(current, replaced); err != nil {
+		return err
+	}
+
+	var merged []containerregistry.BuildArgument
+	for _, a := range current {
+		if !replaced[to.String(a.Name)] {
+			merged = append(merged, a)
+		}
+	}
+	merged = append(merged, newArgs...)
+
+	fmt.Fprintf(os.Stderr, "Updating the build arguments of build step %s...\n", c.step)
+	updated, err := updateBuildArguments(ctx, sc, c.resourceGroupName, c.registryName, c.task, c.step, merged)
+	if err != nil {
+		return err
+	}
+
+	return printBuildArguments(p, c.out, stepBuildArguments(updated))
+}
+
+// parseArgs validates the arguments and flags and converts them into build arguments.
+// Errors never include values, which may be secrets.
+func (c *stepArgsSetCmd) parseArgs() ([]containerregistry.BuildArgument, error) {
+	if err := validateStepTarget(c.resourceGroupName, c.registryName, c.task); err != nil {
+		return nil, err
+	}
+	if c.valueFile != "" && c.valueEnv != "" {
+		return nil, errors.New("--value-file and --value-env can't be used together")
+	}
+	fromSource := c.valueFile != "" || c.valueEnv != ""
+
+	if fromSource || c.secret {
+		if len(c.args) != 1 || strings.Contains(c.args[0], "=") {
+			if c.secret {
+				return nil, errors.New("a secret build argument is set one at a time, by NAME only; its value must be given with --value-file or --value-env")
+			}
+			return nil, errors.New("--value-file and --value-env take the value of a single build argument given by NAME only")
+		}
+		if !fromSource {
+			return nil, errors.New("the value of a secret build argument must be given with --value-file or --value-env")
+		}
+
+		name := c.args[0]
+		if err := validateBuildArgName(name); err != nil {
+			return nil, err
+		}
+		var (
+			value string
+			err   error
+		)
+		if c.valueFile != "" {
+			value, err = readSecretFile(c.valueFile, c.in)
+		} else {
+			value, err = readSecretEnv(c.valueEnv)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []containerregistry.BuildArgument{newBuildArg(name, value, c.secret)}, nil
+	}
+
+	var result []containerregistry.BuildArgument
+	seen := make(map[string]bool, len(c.args))
+	for _, arg := range c.args {
+		kv := strings.SplitN(arg, "=", 2)
+		if err := validateBuildArgName(kv[0]); err != nil {
+			return nil, err
+		}
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("missing value for build argument %q: expected NAME=VALUE", kv[0])
+		}
+		if seen[kv[0]] {
+			return nil, fmt.Errorf("build argument %q is given more than once", kv[0])
+		}
+		seen[kv[0]] = true
+		result = append(result, newBuildArg(kv[0], kv[1], false))
+	}
+	return result, nil
+}
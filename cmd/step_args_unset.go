@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+type stepArgsUnsetCmd struct {
+	resourceGroupName string
+	registryName      string
+	task              string
+	step              string
+	names             []string
+	out               io.Writer
+}
+
+func newStepArgsUnsetCmd(out io.Writer) *cobra.Command {
+	unsetCmd := &stepArgsUnsetCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "unset STEP NAME...",
+		Short: "Remove build arguments from a build step",
+		Long:  "Remove build arguments from a build step.",
+		Args:  args(cobra.MinimumNArgs(2)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			unsetCmd.step = args[0]
+			unsetCmd.names = args[1:]
+			return unsetCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&unsetCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&unsetCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&unsetCmd.task, "task", "", "The name of the build task")
+
+	return cmd
+}
+
+func (c *stepArgsUnsetCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateStepTarget(c.resourceGroupName, c.registryName, c.task); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	current, err := listBuildArguments(ctx, sc, c.resourceGroupName, c.registryName, c.task, c.step)
+	if err != nil {
+		return err
+	}
+
+	removed := make(map[string]bool, len(c.names))
+	for _, name := range c.names {
+		removed[name] = true
+	}
+
+	var (
+		remaining []containerregistry.BuildArgument
+		found     = make(map[string]bool, len(c.names))
+	)
+	for _, a := range current {
+		name := to.String(a.Name)
+		if removed[name] {
+			found[name] = true
+			continue
+		}
+		remaining = append(remaining, a)
+	}
+
+	var missing []string
+	for _, name := range c.names {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return validationError(fmt.Errorf("build step %s has no build argument(s) %s", c.step, strings.Join(missing, ", ")))
+	}
+	if err = checkSecretsPreserved(remaining, nil); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Updating the build arguments of build step %s...\n", c.step)
+	updated, err := updateBuildArguments(ctx, sc, c.resourceGroupName, c.registryName, c.task, c.step, remaining)
+	if err != nil {
+		return err
+	}
+
+	return printBuildArguments(p, c.out, stepBuildArguments(updated))
+}
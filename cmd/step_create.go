@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+const stepCreateLongMessage = `
+Create a Docker build step in a build task.
+
+Base image dependencies are given as [TYPE=]REPOSITORY[:TAG], where TYPE is
+BuildTime (the default) or RunTime. The service also detects them from the
+Dockerfile. Unless --base-image-trigger is None, an update of a base image
+triggers a build.
+
+Examples:
+  solstice step create --rg mygroup --n myregistry --task mytask mystep -t myapp:latest
+  solstice step create --rg mygroup --n myregistry --task mytask mystep -t myapp:latest \
+    --branch release --file docker/Dockerfile --context src --build-arg VERSION=1.0
+`
+
+type stepCreateCmd struct {
+	resourceGroupName string
+	registryName      string
+	task              string
+	name              string
+	branch            string
+	imageName         string
+	noPush            bool
+	dockerfile        string
+	contextPath       string
+	buildArgs         []string
+	secretBuildArgs   []string
+	baseImageTrigger  string
+	baseImages        []string
+	out               io.Writer
+}
+
+func newStepCreateCmd(out io.Writer) *cobra.Command {
+	createCmd := &stepCreateCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a build step",
+		Long:  stepCreateLongMessage,
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			createCmd.name = args[0]
+			return createCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&createCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&createCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&createCmd.task, "task", "", "The name of the build task")
+	f.StringVar(&createCmd.branch, "branch", "master", "The branch of the source repository to build")
+	f.StringVarP(&createCmd.imageName, "image", "t", "", "The name and tag of the image to build, e.g. 'myapp:v1'")
+	f.BoolVar(&createCmd.noPush, "no-push", false, "Build the image without pushing it to the registry")
+	f.StringVarP(&createCmd.dockerfile, "file", "f", "Dockerfile", "The Dockerfile path relative to the context")
+	f.StringVar(&createCmd.contextPath, "context", ".", "The build context path relative to the repository root")
+	f.StringArrayVar(&createCmd.buildArgs, "build-arg", nil, "A build argument in KEY=VALUE form (repeatable)")
+	f.StringArrayVar(&createCmd.secretBuildArgs, "secret-build-arg", nil, "The name of a secret build argument whose value is read from the environment variable of the same name (repeatable)")
+	f.StringVar(&createCmd.baseImageTrigger, "base-image-trigger", string(containerregistry.Runtime), "When base image updates trigger a build: Runtime or None")
+	f.StringArrayVar(&createCmd.baseImages, "base-image", nil, "A base image dependency in [TYPE=]REPOSITORY[:TAG] form (repeatable)")
+
+	return cmd
+}
+
+func (c *stepCreateCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	step, err := c.newBuildStep()
+	if err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Creating build step %s in build task %s...\n", c.name, c.task)
+	future, err := sc.Create(ctx, c.resourceGroupName, c.registryName, c.task, c.name, step)
+	if err != nil {
+		return wrapAPIError("Errored while creating the build step", err)
+	}
+	if err = future.WaitForCompletion(ctx, sc.Client); err != nil {
+		return wrapAPIError("Errored while waiting for the build step to be created", err)
+	}
+	created, err := future.Result(sc)
+	if err != nil {
+		return wrapAPIError("Errored while getting the created build step", err)
+	}
+
+	return printStep(p, c.out, created)
+}
+
+// newBuildStep validates the flags and maps them onto a Docker BuildStep.
+func (c *stepCreateCmd) newBuildStep() (containerregistry.BuildStep, error) {
+	var step containerregistry.BuildStep
+	if err := validateStepTarget(c.resourceGroupName, c.registryName, c.task); err != nil {
+		return step, err
+	}
+	if c.imageName != "" {
+		if err := validateImageName(c.imageName); err != nil {
+			return step, err
+		}
+	}
+
+	trigger, err := parseBaseImageTrigger(c.baseImageTrigger)
+	if err != nil {
+		return step, err
+	}
+	dependencies, err := parseBaseImageDependencies(c.baseImages, trigger != containerregistry.None)
+	if err != nil {
+		return step, err
+	}
+	args, err := parseBuildArgs(c.buildArgs, false)
+	if err != nil {
+		return step, err
+	}
+	secretArgs, err := parseBuildArgs(c.secretBuildArgs, true)
+	if err != nil {
+		return step, err
+	}
+	args = append(args, secretArgs...)
+
+	props := containerregistry.DockerBuildStep{
+		Branch:           to.StringPtr(c.branch),
+		IsPushEnabled:    to.BoolPtr(!c.noPush),
+		DockerFilePath:   to.StringPtr(c.dockerfile),
+		ContextPath:      to.StringPtr(c.contextPath),
+		BaseImageTrigger: trigger,
+	}
+	if c.imageName != "" {
+		props.ImageName = to.StringPtr(c.imageName)
+	}
+	if len(args) > 0 {
+		props.BuildArguments = &args
+	}
+	if len(dependencies) > 0 {
+		props.BaseImageDependencies = &dependencies
+	}
+
+	step.BasicBuildStepProperties = props
+	return step, nil
+}
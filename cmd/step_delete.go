@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+type stepDeleteCmd struct {
+	resourceGroupName string
+	registryName      string
+	task              string
+	names             []string
+	yes               bool
+	in                io.Reader
+	out               io.Writer
+}
+
+func newStepDeleteCmd(out io.Writer) *cobra.Command {
+	deleteCmd := &stepDeleteCmd{
+		in:  os.Stdin,
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "delete NAME...",
+		Short: "Delete build steps",
+		Long:  "Delete one or more steps of a build task after confirmation.",
+		Args:  args(cobra.MinimumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deleteCmd.names = args
+			return deleteCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&deleteCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&deleteCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&deleteCmd.task, "task", "", "The name of the build task")
+	f.BoolVarP(&deleteCmd.yes, "yes", "y", false, "Don't ask for confirmation")
+
+	return cmd
+}
+
+func (c *stepDeleteCmd) run() error {
+	if err := validateStepTarget(c.resourceGroupName, c.registryName, c.task); err != nil {
+		return validationError(err)
+	}
+
+	if !c.yes {
+		ok, err := confirm(c.in, os.Stderr, fmt.Sprintf("Delete build step(s) %s of build task %s?", strings.Join(c.names, ", "), c.task))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Aborted.")
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	for _, name := range c.names {
+		fmt.Fprintf(os.Stderr, "Deleting build step %s...\n", name)
+		future, err := sc.Delete(ctx, c.resourceGroupName, c.registryName, c.task, name)
+		if err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while deleting build step %s", name), err)
+		}
+		if err = future.WaitForCompletion(ctx, sc.Client); err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while waiting for build step %s to be deleted", name), err)
+		}
+		if _, err = future.Result(sc); err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while deleting build step %s", name), err)
+		}
+		fmt.Fprintf(os.Stderr, "Deleted build step %s.\n", name)
+	}
+	return nil
+}
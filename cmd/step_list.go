@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+type stepListCmd struct {
+	resourceGroupName string
+	registryName      string
+	task              string
+	out               io.Writer
+}
+
+func newStepListCmd(out io.Writer) *cobra.Command {
+	listCmd := &stepListCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the steps of a build task",
+		Long:  "List the steps of a build task, sorted by name. Use -o wide for more columns.",
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&listCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&listCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&listCmd.task, "task", "", "The name of the build task")
+
+	return cmd
+}
+
+func (c *stepListCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateStepTarget(c.resourceGroupName, c.registryName, c.task); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Listing the steps of build task %s...\n", c.task)
+	steps, err := listSteps(ctx, sc, c.resourceGroupName, c.registryName, c.task)
+	if err != nil {
+		return err
+	}
+
+	list := view.NewBuildStepList(steps)
+	sort.SliceStable(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	return p.Print(c.out, list, func(w io.Writer) error {
+		return printStepTable(w, list.Items, p.Wide())
+	})
+}
+
+// listSteps returns every step of a build task.
+func listSteps(ctx context.Context, sc containerregistry.BuildStepsClient, resourceGroupName, registryName, task string) ([]containerregistry.BuildStep, error) {
+	iter, err := sc.ListComplete(ctx, resourceGroupName, registryName, task)
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing build steps", err)
+	}
+
+	var steps []containerregistry.BuildStep
+	for ; iter.NotDone(); err = iter.Next() {
+		if err != nil {
+			return nil, wrapAPIError("Errored while listing build steps", err)
+		}
+		steps = append(steps, iter.Value())
+	}
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing build steps", err)
+	}
+	return steps, nil
+}
+
+// printStepTable renders build steps as a table.
+func printStepTable(out io.Writer, steps []view.BuildStep, wide bool) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	header := []string{"NAME", "BRANCH", "IMAGE", "PUSH", "DOCKERFILE", "CONTEXT", "BASE IMAGE TRIGGER"}
+	if wide {
+		header = append(header, "TYPE", "PROVISIONING STATE", "BUILD ARGS", "BASE IMAGES")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, s := range steps {
+		row := []string{
+			s.Name,
+			orDash(s.Branch),
+			orDash(s.ImageName),
+			fmt.Sprintf("%t", s.IsPushEnabled),
+			orDash(s.DockerFilePath),
+			orDash(s.ContextPath),
+			orDash(s.BaseImageTrigger),
+		}
+		if wide {
+			var baseImages []string
+			for _, d := range s.BaseImageDependencies {
+				image := d.Repository
+				if d.Tag != "" {
+					image += ":" + d.Tag
+				}
+				baseImages = append(baseImages, image)
+			}
+			row = append(row,
+				orDash(s.Type),
+				orDash(s.ProvisioningState),
+				fmt.Sprintf("%d", len(s.BuildArguments)),
+				orDash(strings.Join(baseImages, ",")))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+type stepShowCmd struct {
+	resourceGroupName string
+	registryName      string
+	task              string
+	name              string
+	out               io.Writer
+}
+
+func newStepShowCmd(out io.Writer) *cobra.Command {
+	showCmd := &stepShowCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "show NAME",
+		Short: "Show the details of a build step",
+		Long:  "Show the details of a build step. The values of secret build arguments are masked.",
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			showCmd.name = args[0]
+			return showCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&showCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&showCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&showCmd.task, "task", "", "The name of the build task")
+
+	return cmd
+}
+
+func (c *stepShowCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateStepTarget(c.resourceGroupName, c.registryName, c.task); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	step, err := sc.Get(ctx, c.resourceGroupName, c.registryName, c.task, c.name)
+	if err != nil {
+		return wrapAPIError("Errored while getting the build step", err)
+	}
+
+	return printStep(p, c.out, step)
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+const stepUpdateLongMessage = `
+Update a Docker build step.
+
+Only the properties given as flags are changed. Build arguments given with
+--build-arg or --secret-build-arg replace all of the step's build arguments.
+
+Examples:
+  solstice step update --rg mygroup --n myregistry --task mytask mystep --branch release
+  solstice step update --rg mygroup --n myregistry --task mytask mystep --push=false
+`
+
+type stepUpdateCmd struct {
+	resourceGroupName string
+	registryName      string
+	task              string
+	name              string
+	branch            string
+	imageName         string
+	push              bool
+	dockerfile        string
+	contextPath       string
+	buildArgs         []string
+	secretBuildArgs   []string
+	baseImageTrigger  string
+	changed           func(flag string) bool
+	out               io.Writer
+}
+
+func newStepUpdateCmd(out io.Writer) *cobra.Command {
+	updateCmd := &stepUpdateCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "update NAME",
+		Short: "Update a build step",
+		Long:  stepUpdateLongMessage,
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			updateCmd.name = args[0]
+			updateCmd.changed = cmd.Flags().Changed
+			return updateCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&updateCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&updateCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&updateCmd.task, "task", "", "The name of the build task")
+	f.StringVar(&updateCmd.branch, "branch", "", "The branch of the source repository to build")
+	f.StringVarP(&updateCmd.imageName, "image", "t", "", "The name and tag of the image to build, e.g. 'myapp:v1'")
+	f.BoolVar(&updateCmd.push, "push", true, "Push the built image to the registry")
+	f.StringVarP(&updateCmd.dockerfile, "file", "f", "", "The Dockerfile path relative to the context")
+	f.StringVar(&updateCmd.contextPath, "context", "", "The build context path relative to the repository root")
+	f.StringArrayVar(&updateCmd.buildArgs, "build-arg", nil, "A build argument in KEY=VALUE form (repeatable)")
+	f.StringArrayVar(&updateCmd.secretBuildArgs, "secret-build-arg", nil, "The name of a secret build argument whose value is read from the environment variable of the same name (repeatable)")
+	f.StringVar(&updateCmd.baseImageTrigger, "base-image-trigger", "", "When base image updates trigger a build: Runtime or None")
+
+	return cmd
+}
+
+func (c *stepUpdateCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	params, err := c.newUpdateParameters()
+	if err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Updating build step %s...\n", c.name)
+	future, err := sc.Update(ctx, c.resourceGroupName, c.registryName, c.task, c.name, params)
+	if err != nil {
+		return wrapAPIError("Errored while updating the build step", err)
+	}
+	if err = future.WaitForCompletion(ctx, sc.Client); err != nil {
+		return wrapAPIError("Errored while waiting for the build step to be updated", err)
+	}
+	updated, err := future.Result(sc)
+	if err != nil {
+		return wrapAPIError("Errored while getting the updated build step", err)
+	}
+
+	return printStep(p, c.out, updated)
+}
+
+// newUpdateParameters validates the changed flags and maps them onto DockerBuildStepUpdateParameters.
+func (c *stepUpdateCmd) newUpdateParameters() (containerregistry.BuildStepUpdateParameters, error) {
+	var params containerregistry.BuildStepUpdateParameters
+	if err := validateStepTarget(c.resourceGroupName, c.registryName, c.task); err != nil {
+		return params, err
+	}
+
+	props := containerregistry.DockerBuildStepUpdateParameters{}
+	updated := false
+	if c.changed("branch") {
+		if c.branch == "" {
+			return params, errors.New("--branch must not be empty")
+		}
+		props.Branch = to.StringPtr(c.branch)
+		updated = true
+	}
+	if c.changed("image") {
+		if err := validateImageName(c.imageName); err != nil {
+			return params, err
+		}
+		props.ImageName = to.StringPtr(c.imageName)
+		updated = true
+	}
+	if c.changed("push") {
+		props.IsPushEnabled = to.BoolPtr(c.push)
+		updated = true
+	}
+	if c.changed("file") {
+		if c.dockerfile == "" {
+			return params, errors.New("--file must not be empty")
+		}
+		props.DockerFilePath = to.StringPtr(c.dockerfile)
+		updated = true
+	}
+	if c.changed("context") {
+		if c.contextPath == "" {
+			return params, errors.New("--context must not be empty")
+		}
+		props.ContextPath = to.StringPtr(c.contextPath)
+		updated = true
+	}
+	if c.changed("build-arg") || c.changed("secret-build-arg") {
+		args, err := parseBuildArgs(c.buildArgs, false)
+		if err != nil {
+			return params, err
+		}
+		secretArgs, err := parseBuildArgs(c.secretBuildArgs, true)
+		if err != nil {
+			return params, err
+		}
+		// An empty list, rather than null, clears the step's build arguments.
+		args = append([]containerregistry.BuildArgument{}, args...)
+		args = append(args, secretArgs...)
+		props.BuildArguments = &args
+		updated = true
+	}
+	if c.changed("base-image-trigger") {
+		trigger, err := parseBaseImageTrigger(c.baseImageTrigger)
+		if err != nil {
+			return params, err
+		}
+		props.BaseImageTrigger = trigger
+		updated = true
+	}
+
+	if !updated {
+		return params, errors.New("nothing to update, specify at least one property to change")
+	}
+	params.BasicBuildStepPropertiesUpdateParameters = props
+	return params, nil
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/azure/cli"
+	"github.com/ehotinger/solstice/iam"
+	"github.com/ehotinger/solstice/pkg/config"
+)
+
+// subscriptionIDRegexp matches subscription IDs, which are GUIDs.
+var subscriptionIDRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// getSubscription returns the subscription selected with --subscription,
+// AZURE_SUBSCRIPTION_ID or the configuration file, in that order, and the
+// default subscription of `solstice login` or the az profile otherwise.
+// Subscriptions are selected by ID or by name; IDs which aren't known, e.g. on
+// build agents authenticating as a service principal, are used as they are.
+// Names matching no subscription, or several, are validation errors.
+func getSubscription() (*cli.Subscription, error) {
+	selector, from := settings.subscription, "--subscription"
+	if selector == "" {
+		selector, from = os.Getenv("AZURE_SUBSCRIPTION_ID"), "AZURE_SUBSCRIPTION_ID"
+	}
+	if selector == "" {
+		c, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		selector, from = c.Subscription, "the config file"
+	}
+	// AZURE_SUBSCRIPTION_ID always holds an ID.
+	isID := subscriptionIDRegexp.MatchString(selector) || from == "AZURE_SUBSCRIPTION_ID"
+
+	subs, source, err := loadSubscriptions()
+	if err != nil {
+		if isID {
+			return &cli.Subscription{ID: selector}, nil
+		}
+		if selector != "" {
+			return nil, fmt.Errorf("%v; subscriptions can only be selected by name after signing in, pass the subscription's ID instead", err)
+		}
+		return nil, fmt.Errorf("%v; run `solstice login` or `az login`, pass --subscription or set AZURE_SUBSCRIPTION_ID", err)
+	}
+
+	if selector == "" {
+		for _, sub := range subs {
+			if sub.IsDefault {
+				return &sub, nil
+			}
+		}
+		return nil, fmt.Errorf("could not find a default subscription in %s; pass --subscription or set AZURE_SUBSCRIPTION_ID.\n%s", source, listSubscriptions(subs))
+	}
+
+	var matches []cli.Subscription
+	for _, sub := range subs {
+		if strings.EqualFold(sub.ID, selector) {
+			return &sub, nil
+		}
+		if strings.EqualFold(sub.Name, selector) {
+			matches = append(matches, sub)
+		}
+	}
+	switch {
+	case len(matches) == 1:
+		return &matches[0], nil
+	case len(matches) > 1:
+		return nil, validationError(fmt.Errorf("the subscription name %q from %s is ambiguous, select the subscription by ID instead.\n%s", selector, from, listSubscriptions(matches)))
+	case isID:
+		return &cli.Subscription{ID: selector}, nil
+	}
+	return nil, validationError(fmt.Errorf("could not find the subscription %q from %s in %s.\n%s", selector, from, source, listSubscriptions(subs)))
+}
+
+// subscriptionError adds context to an error returned by getSubscription. A
+// selection matching no subscription, or several, keeps its validation exit
+// code; other errors are authentication failures.
+func subscriptionError(err error) error {
+	wrapped := fmt.Errorf("There was an error while grabbing the subscription: %v", err)
+	if e, ok := err.(*exitError); ok {
+		return &exitError{code: e.code, err: wrapped}
+	}
+	return authError(wrapped)
+}
+
+// loadSubscriptions returns the subscriptions saved by `solstice login` when
+// its credentials are used, and those of the az profile otherwise, with a
+// description of where they were found.
+func loadSubscriptions() ([]cli.Subscription, string, error) {
+	if iam.AuthGrantType() == iam.OAuthGrantTypeLogin {
+		subs, err := iam.LoginSubscriptions()
+		return subs, "the subscriptions of `solstice login`", err
+	}
+	profilePath, err := cli.ProfilePath()
+	if err != nil {
+		return nil, "", err
+	}
+	profile, err := cli.LoadProfile(profilePath)
+	return profile.Subscriptions, profilePath, err
+}
+
+// listSubscriptions describes subscriptions for error messages.
+func listSubscriptions(subs []cli.Subscription) string {
+	if len(subs) == 0 {
+		return "There are no subscriptions; run `solstice login` or `az login`."
+	}
+	var b bytes.Buffer
+	b.WriteString("Available subscriptions:")
+	for _, sub := range subs {
+		fmt.Fprintf(&b, "\n  %s  %s", sub.ID, sub.Name)
+		if sub.IsDefault {
+			b.WriteString(" (default)")
+		}
+	}
+	return b.String()
+}
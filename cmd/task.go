@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/ehotinger/solstice/pkg/printer"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const taskLongMessage = `
+Manage build tasks.
+
+A build task builds images from a source repository, either when a commit is
+pushed to it or on demand.
+`
+
+// defaultTaskTimeout is the timeout, in seconds, given to new build tasks.
+const defaultTaskTimeout = 3600
+
+func newTaskCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "task",
+		Short: "Manage build tasks",
+		Long:  taskLongMessage,
+	}
+
+	cmd.AddCommand(
+		newTaskCreateCmd(out),
+		newTaskListCmd(out),
+		newTaskShowCmd(out),
+		newTaskUpdateCmd(out),
+		newTaskDeleteCmd(out),
+		newTaskRunCmd(out),
+		newTaskSourceCmd(out),
+		newTaskExportCmd(out),
+		newTaskImportCmd(out),
+	)
+
+	return cmd
+}
+
+// parseSourceControlType converts a case insensitive source control name into a SourceControlType.
+// "vsts" is accepted as a short name for VisualStudioTeamService.
+func parseSourceControlType(s string) (containerregistry.SourceControlType, error) {
+	if strings.EqualFold(s, "vsts") {
+		return containerregistry.VisualStudioTeamService, nil
+	}
+	var names []string
+	for _, t := range containerregistry.PossibleSourceControlTypeValues() {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+		names = append(names, string(t))
+	}
+	return "", fmt.Errorf("invalid source control type %q: it must be one of %s", s, strings.Join(names, ", "))
+}
+
+// parseTaskStatus converts a case insensitive status name into a BuildTaskStatus.
+func parseTaskStatus(s string) (containerregistry.BuildTaskStatus, error) {
+	var names []string
+	for _, status := range containerregistry.PossibleBuildTaskStatusValues() {
+		if strings.EqualFold(s, string(status)) {
+			return status, nil
+		}
+		names = append(names, string(status))
+	}
+	return "", fmt.Errorf("invalid build task status %q: it must be one of %s", s, strings.Join(names, ", "))
+}
+
+// parseTokenType converts a case insensitive token type name into a TokenType.
+func parseTokenType(s string) (containerregistry.TokenType, error) {
+	var names []string
+	for _, t := range containerregistry.PossibleTokenTypeValues() {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+		names = append(names, string(t))
+	}
+	return "", fmt.Errorf("invalid token type %q: it must be one of %s", s, strings.Join(names, ", "))
+}
+
+// parseTags converts KEY=VALUE pairs into resource tags.
+func parseTags(tags []string) (map[string]*string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]*string, len(tags))
+	for _, tag := range tags {
+		kv := strings.SplitN(tag, "=", 2)
+		if kv[0] == "" || len(kv) != 2 {
+			return nil, fmt.Errorf("invalid tag %q: expected KEY=VALUE", tag)
+		}
+		value := kv[1]
+		result[kv[0]] = &value
+	}
+	return result, nil
+}
+
+// printTask writes a build task to out using the printer's format.
+func printTask(p *printer.Printer, out io.Writer, task containerregistry.BuildTask) error {
+	v := view.NewBuildTask(task)
+	return p.Print(out, v, func(w io.Writer) error {
+		return printTaskDetails(w, v)
+	})
+}
+
+// printTaskDetails renders a single build task as a list of fields.
+func printTaskDetails(out io.Writer, t view.BuildTask) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", t.Name)
+	fmt.Fprintf(w, "Alias:\t%s\n", orDash(t.Alias))
+	fmt.Fprintf(w, "Status:\t%s\n", orDash(t.Status))
+	fmt.Fprintf(w, "Provisioning State:\t%s\n", orDash(t.ProvisioningState))
+	fmt.Fprintf(w, "Location:\t%s\n", orDash(t.Location))
+	fmt.Fprintf(w, "Created:\t%s\n", formatTime(t.CreationTime))
+	fmt.Fprintf(w, "Platform:\t%s\n", formatPlatform(t.Platform))
+	fmt.Fprintf(w, "Timeout:\t%s\n", formatTimeout(t.TimeoutSeconds))
+	if r := t.SourceRepository; r != nil {
+		fmt.Fprintf(w, "Source Control:\t%s\n", orDash(r.SourceControlType))
+		fmt.Fprintf(w, "Repository:\t%s\n", orDash(r.RepositoryURL))
+		fmt.Fprintf(w, "Commit Trigger:\t%s\n", enabledString(r.IsCommitTriggerEnabled))
+	}
+	for _, k := range sortedKeys(t.Tags) {
+		fmt.Fprintf(w, "Tag:\t%s=%s\n", k, t.Tags[k])
+	}
+	return w.Flush()
+}
+
+func formatTimeout(seconds *int32) string {
+	if seconds == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%ds", *seconds)
+}
+
+func enabledString(enabled bool) string {
+	if enabled {
+		return "Enabled"
+	}
+	return "Disabled"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+const taskCreateLongMessage = `
+Create a build task.
+
+The service needs a token with access to the source repository to register the
+commit trigger. It is read from the file given with --token-file, or from stdin
+if the file is '-', so that it never appears in the shell history.
+
+The --location of the task should be the location of the registry.
+
+Examples:
+  solstice task create --rg mygroup --n myregistry mytask --location westus \
+    --repo-url https://github.com/org/repo --token-file ~/.tokens/github
+  cat token | solstice task create --rg mygroup --n myregistry mytask --location westus \
+    --source-control vsts --repo-url https://org.visualstudio.com/_git/repo --token-file -
+`
+
+type taskCreateCmd struct {
+	resourceGroupName string
+	registryName      string
+	name              string
+	alias             string
+	status            string
+	sourceControl     string
+	repositoryURL     string
+	tokenFile         string
+	tokenType         string
+	commitTrigger     bool
+	osType            string
+	cpu               int32
+	timeout           int32
+	location          string
+	tags              []string
+	in                io.Reader
+	out               io.Writer
+}
+
+func newTaskCreateCmd(out io.Writer) *cobra.Command {
+	createCmd := &taskCreateCmd{
+		in:  os.Stdin,
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a build task",
+		Long:  taskCreateLongMessage,
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			createCmd.name = args[0]
+			return createCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&createCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&createCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&createCmd.alias, "alias", "", "The alias of the build task (defaults to its name)")
+	f.StringVar(&createCmd.status, "status", string(containerregistry.Enabled), "The status of the build task: Enabled or Disabled")
+	f.StringVar(&createCmd.sourceControl, "source-control", string(containerregistry.Github), "The type of source control: Github or VisualStudioTeamService (vsts)")
+	f.StringVar(&createCmd.repositoryURL, "repo-url", "", "The URL of the source repository")
+	f.StringVar(&createCmd.tokenFile, "token-file", "", "A file containing the source control token, or '-' to read it from stdin")
+	f.StringVar(&createCmd.tokenType, "token-type", string(containerregistry.PAT), "The type of the source control token: PAT or OAuth")
+	f.BoolVar(&createCmd.commitTrigger, "commit-trigger", true, "Build whenever a commit is pushed to the repository")
+	f.StringVar(&createCmd.osType, "os", "linux", "The operating system to build on: linux or windows")
+	f.Int32Var(&createCmd.cpu, "cpu", 0, "The number of CPU cores to build with (defaults to the service's choice)")
+	f.Int32Var(&createCmd.timeout, "timeout", defaultTaskTimeout, fmt.Sprintf("The build timeout in seconds (%d-%d)", minTimeout, maxTimeout))
+	f.StringVar(&createCmd.location, "location", "", "The location of the build task, e.g. westus")
+	f.StringArrayVar(&createCmd.tags, "tag", nil, "A tag in KEY=VALUE form (repeatable)")
+	markStdinFlags(f, "token-file")
+
+	return cmd
+}
+
+func (c *taskCreateCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	task, err := c.newBuildTask()
+	if err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	fmt.Fprintf(os.Stderr, "Creating build task %s...\n", c.name)
+	future, err := tc.Create(ctx, c.resourceGroupName, c.registryName, c.name, task)
+	if err != nil {
+		return wrapAPIError("Errored while creating the build task", err)
+	}
+	if err = future.WaitForCompletion(ctx, tc.Client); err != nil {
+		return wrapAPIError("Errored while waiting for the build task to be created", err)
+	}
+	created, err := future.Result(tc)
+	if err != nil {
+		return wrapAPIError("Errored while getting the created build task", err)
+	}
+
+	return printTask(p, c.out, created)
+}
+
+// newBuildTask validates the flags and maps them onto a BuildTask.
+func (c *taskCreateCmd) newBuildTask() (containerregistry.BuildTask, error) {
+	var task containerregistry.BuildTask
+	if err := validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return task, err
+	}
+	if c.repositoryURL == "" {
+		return task, errors.New("a source repository must be specified with --repo-url")
+	}
+	if c.location == "" {
+		return task, errors.New("a location must be specified with --location")
+	}
+	if c.tokenFile == "" {
+		return task, errors.New("a source control token must be specified with --token-file")
+	}
+	if err := validateTimeout(c.timeout); err != nil {
+		return task, err
+	}
+
+	status, err := parseTaskStatus(c.status)
+	if err != nil {
+		return task, err
+	}
+	sourceControlType, err := parseSourceControlType(c.sourceControl)
+	if err != nil {
+		return task, err
+	}
+	tokenType, err := parseTokenType(c.tokenType)
+	if err != nil {
+		return task, err
+	}
+	platform, err := parsePlatform(c.osType, c.cpu)
+	if err != nil {
+		return task, err
+	}
+	tags, err := parseTags(c.tags)
+	if err != nil {
+		return task, err
+	}
+	token, err := readSecretFile(c.tokenFile, c.in)
+	if err != nil {
+		return task, err
+	}
+
+	alias := c.alias
+	if alias == "" {
+		alias = c.name
+	}
+
+	task = containerregistry.BuildTask{
+		Tags: tags,
+		BuildTaskProperties: &containerregistry.BuildTaskProperties{
+			Alias:  to.StringPtr(alias),
+			Status: status,
+			SourceRepository: &containerregistry.SourceRepositoryProperties{
+				SourceControlType:      sourceControlType,
+				RepositoryURL:          to.StringPtr(c.repositoryURL),
+				IsCommitTriggerEnabled: to.BoolPtr(c.commitTrigger),
+				SourceControlAuthProperties: &containerregistry.SourceControlAuthInfo{
+					TokenType: tokenType,
+					Token:     to.StringPtr(token),
+				},
+			},
+			Platform: platform,
+			Timeout:  to.Int32Ptr(c.timeout),
+		},
+		Location: to.StringPtr(c.location),
+	}
+	return task, nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+type taskDeleteCmd struct {
+	resourceGroupName string
+	registryName      string
+	names             []string
+	yes               bool
+	in                io.Reader
+	out               io.Writer
+}
+
+func newTaskDeleteCmd(out io.Writer) *cobra.Command {
+	deleteCmd := &taskDeleteCmd{
+		in:  os.Stdin,
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "delete NAME...",
+		Short: "Delete build tasks",
+		Long:  "Delete one or more build tasks, along with their steps, after confirmation.",
+		Args:  args(cobra.MinimumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deleteCmd.names = args
+			return deleteCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&deleteCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&deleteCmd.registryName, "n", "", "The name of the registry")
+	f.BoolVarP(&deleteCmd.yes, "yes", "y", false, "Don't ask for confirmation")
+
+	return cmd
+}
+
+func (c *taskDeleteCmd) run() error {
+	if err := validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return validationError(err)
+	}
+
+	if !c.yes {
+		ok, err := confirm(c.in, os.Stderr, fmt.Sprintf("Delete build task(s) %s?", strings.Join(c.names, ", ")))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Aborted.")
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	for _, name := range c.names {
+		fmt.Fprintf(os.Stderr, "Deleting build task %s...\n", name)
+		future, err := tc.Delete(ctx, c.resourceGroupName, c.registryName, name)
+		if err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while deleting build task %s", name), err)
+		}
+		if err = future.WaitForCompletion(ctx, tc.Client); err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while waiting for build task %s to be deleted", name), err)
+		}
+		if _, err = future.Result(tc); err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while deleting build task %s", name), err)
+		}
+		fmt.Fprintf(os.Stderr, "Deleted build task %s.\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/manifest"
+	"github.com/spf13/cobra"
+)
+
+const taskExportLongMessage = `
+Export build tasks, their steps and their build arguments as a manifest.
+
+The manifest has the format read by 'solstice apply' and 'solstice task
+import', and is written as YAML unless another --output format is selected.
+Without build task names, every build task of the registry is exported.
+Build tasks which couldn't be imported again, such as those without a source
+repository, are skipped with a warning.
+
+Secrets are never exported. Each source control token and secret build
+argument is replaced by an environment variable, named after the task, step
+and argument, from which 'solstice task import' reads it. The variables are
+listed on stderr.
+
+Examples:
+  solstice task export --rg mygroup --n myregistry > tasks.yaml
+  solstice task export --rg mygroup --n myregistry mytask -o json
+`
+
+// envNameRegexp matches the characters which can't be part of an environment variable name.
+var envNameRegexp = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+type taskExportCmd struct {
+	resourceGroupName string
+	registryName      string
+	names             []string
+	out               io.Writer
+}
+
+func newTaskExportCmd(out io.Writer) *cobra.Command {
+	exportCmd := &taskExportCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "export [NAME...]",
+		Short: "Export build tasks as a manifest",
+		Long:  taskExportLongMessage,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exportCmd.names = args
+			return exportCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&exportCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&exportCmd.registryName, "n", "", "The name of the registry")
+
+	return cmd
+}
+
+func (c *taskExportCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*5)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+	sc, err := client.GetBuildStepsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	names := c.names
+	if len(names) == 0 {
+		if names, err = listTaskNames(ctx, tc, c.resourceGroupName, c.registryName); err != nil {
+			return err
+		}
+	}
+
+	m := &manifest.Manifest{
+		APIVersion: manifest.APIVersion,
+		BuildTasks: []manifest.BuildTask{},
+	}
+	var envs []string
+	for _, name := range names {
+		task, err := tc.Get(ctx, c.resourceGroupName, c.registryName, name)
+		if err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while getting build task %s", name), err)
+		}
+		steps, err := listSteps(ctx, sc, c.resourceGroupName, c.registryName, name)
+		if err != nil {
+			return err
+		}
+
+		t, skipped := exportTask(task, steps)
+		// Tasks which couldn't be imported again, e.g. without a source repository, are left out.
+		single := manifest.Manifest{APIVersion: manifest.APIVersion, BuildTasks: []manifest.BuildTask{t}}
+		if err = single.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v, so it wasn't exported.\n", err)
+			continue
+		}
+		for _, s := range skipped {
+			fmt.Fprintf(os.Stderr, "Warning: step %s of build task %s isn't a Docker build step and wasn't exported.\n", s, t.Name)
+		}
+		m.BuildTasks = append(m.BuildTasks, t)
+		envs = append(envs, manifestSecretEnvs(t)...)
+	}
+
+	if len(envs) > 0 {
+		fmt.Fprintf(os.Stderr, "Secrets weren't exported. Set these environment variables before importing the manifest: %s\n", strings.Join(envs, ", "))
+	}
+	return p.Print(c.out, m, func(w io.Writer) error {
+		b, err := manifest.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+}
+
+// exportTask converts a build task and its steps into a manifest entry, replacing
+// secrets with environment variables. It returns the names of the steps which
+// can't be part of a manifest.
+func exportTask(task containerregistry.BuildTask, steps []containerregistry.BuildStep) (manifest.BuildTask, []string) {
+	name := to.String(task.Name)
+	t := manifest.BuildTask{
+		Name:     name,
+		Location: to.String(task.Location),
+		Source: manifest.Source{
+			TokenEnv: secretEnvName(name, "TOKEN"),
+		},
+	}
+	if len(task.Tags) > 0 {
+		t.Tags = make(map[string]string, len(task.Tags))
+		for k, v := range task.Tags {
+			if k != tokenRotatedTag {
+				t.Tags[k] = to.String(v)
+			}
+		}
+		if len(t.Tags) == 0 {
+			t.Tags = nil
+		}
+	}
+	if props := task.BuildTaskProperties; props != nil {
+		t.Alias = to.String(props.Alias)
+		t.Status = string(props.Status)
+		t.Timeout = props.Timeout
+		if props.Platform != nil {
+			t.Platform = &manifest.Platform{
+				OS:  strings.ToLower(string(props.Platform.OsType)),
+				CPU: props.Platform.CPU,
+			}
+		}
+		if source := props.SourceRepository; source != nil {
+			t.Source.Type = string(source.SourceControlType)
+			t.Source.RepositoryURL = to.String(source.RepositoryURL)
+			t.Source.CommitTrigger = source.IsCommitTriggerEnabled
+			if auth := source.SourceControlAuthProperties; auth != nil {
+				t.Source.TokenType = string(auth.TokenType)
+			}
+		}
+	}
+
+	var skipped []string
+	for _, step := range steps {
+		if step.BasicBuildStepProperties == nil {
+			skipped = append(skipped, to.String(step.Name))
+			continue
+		}
+		d, ok := step.BasicBuildStepProperties.AsDockerBuildStep()
+		if !ok {
+			skipped = append(skipped, to.String(step.Name))
+			continue
+		}
+
+		s := manifest.Step{
+			Name:             to.String(step.Name),
+			Branch:           to.String(d.Branch),
+			Image:            to.String(d.ImageName),
+			Push:             d.IsPushEnabled,
+			Dockerfile:       to.String(d.DockerFilePath),
+			Context:          to.String(d.ContextPath),
+			BaseImageTrigger: string(d.BaseImageTrigger),
+			BuildArgs:        []manifest.BuildArg{},
+		}
+		if d.BaseImageDependencies != nil {
+			for _, dep := range *d.BaseImageDependencies {
+				s.BaseImages = append(s.BaseImages, formatBaseImageDependency(dep))
+			}
+		}
+		for _, a := range stepBuildArguments(step) {
+			arg := manifest.BuildArg{Name: to.String(a.Name)}
+			if to.Bool(a.IsSecret) {
+				arg.Secret = true
+				arg.ValueEnv = secretEnvName(name, s.Name, arg.Name)
+			} else {
+				arg.Value = to.String(a.Value)
+			}
+			s.BuildArgs = append(s.BuildArgs, arg)
+		}
+		t.Steps = append(t.Steps, s)
+	}
+	return t, skipped
+}
+
+// manifestSecretEnvs lists the environment variables a manifest entry reads secrets from.
+func manifestSecretEnvs(t manifest.BuildTask) []string {
+	var envs []string
+	if t.Source.TokenEnv != "" {
+		envs = append(envs, t.Source.TokenEnv)
+	}
+	for _, s := range t.Steps {
+		for _, a := range s.BuildArgs {
+			if a.Secret && a.ValueEnv != "" {
+				envs = append(envs, a.ValueEnv)
+			}
+		}
+	}
+	return envs
+}
+
+// secretEnvName builds the name of the environment variable holding an exported
+// secret, e.g. SOLSTICE_MYTASK_MYSTEP_NPM_TOKEN.
+func secretEnvName(parts ...string) string {
+	name := "SOLSTICE_" + strings.ToUpper(strings.Join(parts, "_"))
+	return envNameRegexp.ReplaceAllString(name, "_")
+}
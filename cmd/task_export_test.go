@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/pkg/manifest"
+)
+
+func TestExportTaskRoundTrip(t *testing.T) {
+	task := planTestTask("webapp", map[string]*string{
+		"team":          to.StringPtr("web"),
+		tokenRotatedTag: to.StringPtr("2018-01-01T00:00:00Z"),
+	})
+	step := planTestStep("webappstep",
+		containerregistry.BuildArgument{Name: to.StringPtr("VERSION"), Value: to.StringPtr("2"), IsSecret: to.BoolPtr(false)},
+		containerregistry.BuildArgument{Name: to.StringPtr("npm.token"), Value: to.StringPtr("*****"), IsSecret: to.BoolPtr(true)},
+	)
+	docker := step.BasicBuildStepProperties.(containerregistry.DockerBuildStep)
+	docker.BaseImageDependencies = &[]containerregistry.BaseImageDependency{
+		{Type: containerregistry.BuildTime, RepositoryName: to.StringPtr("node"), Tag: to.StringPtr("8"), IsAutoTriggerEnabled: to.BoolPtr(true)},
+		{Type: containerregistry.RunTime, RepositoryName: to.StringPtr("alpine"), IsAutoTriggerEnabled: to.BoolPtr(true)},
+	}
+	step.BasicBuildStepProperties = docker
+	other := containerregistry.BuildStep{Name: to.StringPtr("otherstep"), BasicBuildStepProperties: containerregistry.BuildStepProperties{}}
+
+	exported, skipped := exportTask(task, []containerregistry.BuildStep{step, other})
+	if !reflect.DeepEqual(skipped, []string{"otherstep"}) {
+		t.Errorf("skipped steps = %q, want the step which isn't a Docker build step", skipped)
+	}
+
+	data, err := manifest.Marshal(&manifest.Manifest{APIVersion: manifest.APIVersion, BuildTasks: []manifest.BuildTask{exported}})
+	if err != nil {
+		t.Fatalf("failed to marshal the manifest: %v", err)
+	}
+	m, err := manifest.Parse(data)
+	if err != nil {
+		t.Fatalf("failed to parse the exported manifest: %v\n%s", err, data)
+	}
+
+	got := m.BuildTasks[0]
+	if got.Source.TokenEnv != "SOLSTICE_WEBAPP_TOKEN" {
+		t.Errorf("the token is read from %q", got.Source.TokenEnv)
+	}
+	if !reflect.DeepEqual(got.Tags, map[string]string{"team": "web"}) {
+		t.Errorf("tags = %v, want only the team tag", got.Tags)
+	}
+	if len(got.Steps) != 1 {
+		t.Fatalf("got %d steps, want 1:\n%s", len(got.Steps), data)
+	}
+	wantArgs := []manifest.BuildArg{
+		{Name: "VERSION", Value: "2"},
+		{Name: "npm.token", Secret: true, ValueEnv: "SOLSTICE_WEBAPP_WEBAPPSTEP_NPM_TOKEN"},
+	}
+	if !reflect.DeepEqual(got.Steps[0].BuildArgs, wantArgs) {
+		t.Errorf("build args = %+v, want %+v", got.Steps[0].BuildArgs, wantArgs)
+	}
+	wantBaseImages := []string{"BuildTime=node:8", "RunTime=alpine"}
+	if !reflect.DeepEqual(got.Steps[0].BaseImages, wantBaseImages) {
+		t.Errorf("base images = %q, want %q", got.Steps[0].BaseImages, wantBaseImages)
+	}
+
+	// Applying the export to the registry it came from changes nothing.
+	state := newPlanTestState([]containerregistry.BuildTask{task}, map[string][]containerregistry.BuildStep{"webapp": {step}})
+	changes, err := (&planner{}).plan(m, state)
+	if err != nil {
+		t.Fatalf("plan errored: %v", err)
+	}
+	if len(changes) > 0 {
+		t.Errorf("the exported manifest differs from the registry: %q", planSummary(changes))
+	}
+
+	// Importing it into an empty registry recreates the base images.
+	created, err := newStepFromManifest(&got.Steps[0], nil)
+	if err != nil {
+		t.Fatalf("newStepFromManifest errored: %v", err)
+	}
+	createdDocker, _ := created.BasicBuildStepProperties.AsDockerBuildStep()
+	if createdDocker.BaseImageDependencies == nil || baseImageList(*createdDocker.BaseImageDependencies) != baseImageList(*docker.BaseImageDependencies) {
+		t.Errorf("the created step's base images differ from the exported step's")
+	}
+}
+
+func TestSecretEnvName(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"webapp", "TOKEN"}, "SOLSTICE_WEBAPP_TOKEN"},
+		{[]string{"webapp", "webappstep", "npm.token"}, "SOLSTICE_WEBAPP_WEBAPPSTEP_NPM_TOKEN"},
+		{[]string{"webapp", "step", "a--b c"}, "SOLSTICE_WEBAPP_STEP_A_B_C"},
+	}
+	for _, tt := range tests {
+		if got := secretEnvName(tt.parts...); got != tt.want {
+			t.Errorf("secretEnvName(%q) = %q, want %q", tt.parts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const taskImportLongMessage = `
+Create the build tasks and steps of a manifest in a registry.
+
+The manifest is typically written by 'solstice task export', possibly for
+another registry or subscription: importing it restores a registry's build
+tasks, or copies them to another registry. Build tasks and steps which already
+exist are left alone, unless --update is given, in which case they're changed
+to match the manifest like 'solstice apply' does.
+
+Source control tokens and secret build arguments are read from the files or
+environment variables named in the manifest.
+
+Examples:
+  solstice task export --rg prod --n prodregistry > tasks.yaml
+  solstice task import --rg staging --n stagingregistry -f tasks.yaml
+  solstice task import --subscription 00000000-0000-0000-0000-000000000000 \
+    --rg dr --n drregistry -f tasks.yaml --yes
+`
+
+type taskImportCmd struct {
+	applyCmd
+	update bool
+}
+
+func newTaskImportCmd(out io.Writer) *cobra.Command {
+	importCmd := &taskImportCmd{
+		applyCmd: applyCmd{
+			in:  os.Stdin,
+			out: out,
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Create build tasks from a manifest",
+		Long:  taskImportLongMessage,
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&importCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&importCmd.registryName, "n", "", "The name of the registry")
+	f.StringVarP(&importCmd.file, "file", "f", "", "The manifest file, or '-' to read it from stdin")
+	f.BoolVar(&importCmd.update, "update", false, "Also update existing build tasks and steps to match the manifest")
+	f.BoolVar(&importCmd.dryRun, "dry-run", false, "Only show the plan, without making any changes")
+	f.BoolVarP(&importCmd.yes, "yes", "y", false, "Don't ask for confirmation")
+	markStdinFlags(f, "file")
+
+	return cmd
+}
+
+func (c *taskImportCmd) run() error {
+	c.createOnly = !c.update
+	return c.applyCmd.run()
+}
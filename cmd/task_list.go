@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+type taskListCmd struct {
+	resourceGroupName string
+	registryName      string
+	out               io.Writer
+}
+
+func newTaskListCmd(out io.Writer) *cobra.Command {
+	listCmd := &taskListCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List build tasks",
+		Long:  "List the build tasks of a registry, sorted by name. Use -o wide for more columns.",
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&listCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&listCmd.registryName, "n", "", "The name of the registry")
+
+	return cmd
+}
+
+func (c *taskListCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	fmt.Fprintln(os.Stderr, "Listing build tasks...")
+	tasks, err := listTasks(ctx, tc, c.resourceGroupName, c.registryName)
+	if err != nil {
+		return err
+	}
+
+	list := view.NewBuildTaskList(tasks)
+	sort.SliceStable(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	return p.Print(c.out, list, func(w io.Writer) error {
+		return printTaskTable(w, list.Items, p.Wide())
+	})
+}
+
+// listTasks returns every build task of a registry.
+func listTasks(ctx context.Context, tc containerregistry.BuildTasksClient, resourceGroupName, registryName string) ([]containerregistry.BuildTask, error) {
+	iter, err := tc.ListComplete(ctx, resourceGroupName, registryName, "", "")
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing build tasks", err)
+	}
+
+	var tasks []containerregistry.BuildTask
+	for ; iter.NotDone(); err = iter.Next() {
+		if err != nil {
+			return nil, wrapAPIError("Errored while listing build tasks", err)
+		}
+		tasks = append(tasks, iter.Value())
+	}
+	if err != nil {
+		return nil, wrapAPIError("Errored while listing build tasks", err)
+	}
+	return tasks, nil
+}
+
+// listTaskNames returns the sorted names of every build task of a registry.
+func listTaskNames(ctx context.Context, tc containerregistry.BuildTasksClient, resourceGroupName, registryName string) ([]string, error) {
+	tasks, err := listTasks(ctx, tc, resourceGroupName, registryName)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		if t.Name != nil {
+			names = append(names, *t.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// printTaskTable renders build tasks as a table.
+func printTaskTable(out io.Writer, tasks []view.BuildTask, wide bool) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	header := []string{"NAME", "ALIAS", "STATUS", "PLATFORM", "REPOSITORY", "COMMIT TRIGGER", "TIMEOUT"}
+	if wide {
+		header = append(header, "SOURCE CONTROL", "PROVISIONING STATE", "LOCATION", "CREATED")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, t := range tasks {
+		repository, trigger, sourceControl := "-", "-", "-"
+		if r := t.SourceRepository; r != nil {
+			repository = orDash(r.RepositoryURL)
+			trigger = enabledString(r.IsCommitTriggerEnabled)
+			sourceControl = orDash(r.SourceControlType)
+		}
+
+		row := []string{
+			t.Name,
+			orDash(t.Alias),
+			orDash(t.Status),
+			formatPlatform(t.Platform),
+			repository,
+			trigger,
+			formatTimeout(t.TimeoutSeconds),
+		}
+		if wide {
+			row = append(row,
+				sourceControl,
+				orDash(t.ProvisioningState),
+				orDash(t.Location),
+				formatTime(t.CreationTime))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+const taskRunLongMessage = `
+Queue a build of a build task.
+
+The build runs every step of the task against the current state of its source
+repository. By default the command returns once the build is queued. With
+--wait or --follow it waits for the build to finish and exits with a code
+reflecting its final status, like 'solstice build' (see 'solstice help').
+
+Examples:
+  solstice task run --rg mygroup --n myregistry mytask
+  solstice task run --rg mygroup --n myregistry mytask --follow
+`
+
+type taskRunCmd struct {
+	resourceGroupName string
+	registryName      string
+	name              string
+	wait              bool
+	follow            bool
+	out               io.Writer
+}
+
+func newTaskRunCmd(out io.Writer) *cobra.Command {
+	runCmd := &taskRunCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "run NAME",
+		Short: "Queue a build of a build task",
+		Long:  taskRunLongMessage,
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runCmd.name = args[0]
+			return runCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&runCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&runCmd.registryName, "n", "", "The name of the registry")
+	f.BoolVar(&runCmd.wait, "wait", false, "Wait for the build to finish")
+	f.BoolVar(&runCmd.follow, "follow", false, "Stream the build's logs until it finishes (implies --wait)")
+
+	return cmd
+}
+
+func (c *taskRunCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	rc, err := client.GetRegistriesClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("could not get registry client: %v", err))
+	}
+
+	req := containerregistry.BuildTaskBuildRequest{
+		BuildTaskName: to.StringPtr(c.name),
+		Type:          containerregistry.TypeBuildTask,
+	}
+	bas, ok := req.AsBasicQueueBuildRequest()
+	if !ok {
+		return errors.New("Failed to create build task request")
+	}
+
+	fin, err := queueBuild(ctx, rc, c.resourceGroupName, c.registryName, bas)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Queued build %s of build task %s.\n", *fin.BuildID, c.name)
+	if !c.wait && !c.follow {
+		return printBuild(p, c.out, fin)
+	}
+
+	bc, err := client.GetBuildsClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+	final, err := awaitBuild(context.Background(), bc, c.resourceGroupName, c.registryName, *fin.BuildID, c.follow, logWriter(p, c.out))
+	if err != nil {
+		return err
+	}
+	if err = printBuild(p, c.out, final); err != nil {
+		return err
+	}
+	return buildStatusError(*fin.BuildID, final.Status)
+}
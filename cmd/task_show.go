@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+type taskShowCmd struct {
+	resourceGroupName string
+	registryName      string
+	name              string
+	out               io.Writer
+}
+
+func newTaskShowCmd(out io.Writer) *cobra.Command {
+	showCmd := &taskShowCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "show NAME",
+		Short: "Show the details of a build task",
+		Long:  "Show the details of a build task. Source control credentials are never shown.",
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			showCmd.name = args[0]
+			return showCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&showCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&showCmd.registryName, "n", "", "The name of the registry")
+
+	return cmd
+}
+
+func (c *taskShowCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	task, err := tc.Get(ctx, c.resourceGroupName, c.registryName, c.name)
+	if err != nil {
+		return wrapAPIError("Errored while getting the build task", err)
+	}
+
+	return printTask(p, c.out, task)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const taskSourceLongMessage = `
+Inspect and rotate the source control credentials of build tasks.
+
+Build tasks use a token to access their source repository. Once it expires,
+commit triggers stop working without notice, so solstice warns about tokens
+expiring within --warn-within (7 days by default).
+
+The service only stores the lifetime of a token, given with --expires-in, so
+'rotate' records when it replaced the token in the build task's
+` + tokenRotatedTag + ` tag. The expiry of tokens which weren't rotated with
+solstice is unknown.
+`
+
+// defaultTokenExpiryWarning is how close to its expiry a token is reported.
+const defaultTokenExpiryWarning = 7 * 24 * time.Hour
+
+// tokenRotatedTag is the build task tag in which 'task source rotate' records
+// when the token was replaced, as an RFC 3339 time. It's owned by solstice:
+// tags replaced by 'task update' and 'apply' keep it, and 'task export' skips it.
+const tokenRotatedTag = "solstice-token-rotated"
+
+// tokenRotatedAt returns the time recorded in a build task's tokenRotatedTag, if any.
+func tokenRotatedAt(tags map[string]*string) *time.Time {
+	v, ok := tags[tokenRotatedTag]
+	if !ok || v == nil {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *v)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// keepTokenRotatedTag adds the current tokenRotatedTag of a build task to the
+// tags replacing its tags. Nil tags, which leave the task's tags alone, stay nil.
+func keepTokenRotatedTag(tags map[string]*string, current map[string]*string) map[string]*string {
+	v, ok := current[tokenRotatedTag]
+	if tags == nil || !ok {
+		return tags
+	}
+	if _, ok := tags[tokenRotatedTag]; !ok {
+		tags[tokenRotatedTag] = v
+	}
+	return tags
+}
+
+// Values of view.TokenRotation.Result.
+const (
+	rotationDone   = "Rotated"
+	rotationFailed = "Failed"
+)
+
+func newTaskSourceCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "source",
+		Short: "Inspect and rotate source control credentials",
+		Long:  taskSourceLongMessage,
+	}
+
+	cmd.AddCommand(
+		newTaskSourceShowCmd(out),
+		newTaskSourceRotateCmd(out),
+	)
+
+	return cmd
+}
+
+// warnExpiringTokens writes a warning to stderr for every token which expires within the given duration.
+func warnExpiringTokens(sources []view.TaskSource, within time.Duration, now time.Time) {
+	for _, s := range sources {
+		if expiresIn, ok := s.ExpiresIn(now); ok && expiresIn <= within {
+			if expiresIn <= 0 {
+				fmt.Fprintf(os.Stderr, "Warning: the source control token of build task %s has expired. Rotate it with 'solstice task source rotate'.\n", s.BuildTask)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Warning: the source control token of build task %s expires in %s. Rotate it with 'solstice task source rotate'.\n", s.BuildTask, humanDuration(expiresIn))
+		}
+	}
+}
+
+// printTaskSourceTable renders the source repositories of build tasks as a table.
+func printTaskSourceTable(out io.Writer, sources []view.TaskSource, wide bool, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	header := []string{"TASK", "SOURCE CONTROL", "REPOSITORY", "COMMIT TRIGGER", "TOKEN TYPE", "TOKEN LIFETIME", "EXPIRES IN"}
+	if wide {
+		header = append(header, "ROTATED", "SCOPE", "REFRESH TOKEN")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, s := range sources {
+		lifetime, expires, rotated := "-", "-", "-"
+		if d, ok := s.Lifetime(); ok {
+			lifetime = humanDuration(d)
+		}
+		if d, ok := s.ExpiresIn(now); ok {
+			expires = "expired"
+			if d > 0 {
+				expires = humanDuration(d)
+			}
+		}
+		if s.TokenRotatedAt != nil {
+			rotated = humanDuration(now.Sub(*s.TokenRotatedAt)) + " ago"
+		}
+		row := []string{
+			s.BuildTask,
+			orDash(s.SourceControlType),
+			orDash(s.RepositoryURL),
+			enabledString(s.IsCommitTriggerEnabled),
+			orDash(s.TokenType),
+			lifetime,
+			expires,
+		}
+		if wide {
+			row = append(row, rotated, orDash(s.Scope), fmt.Sprintf("%t", s.HasRefreshToken))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
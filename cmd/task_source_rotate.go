@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const taskSourceRotateLongMessage = `
+Replace the source control token of one or more build tasks.
+
+The new token is read from the file given with --token-file, or from stdin if
+the file is '-'. Tasks are given by name, or selected with --all, optionally
+narrowed down to the tasks building a single repository with --repo-url.
+
+The time of the rotation is recorded in the build task's ` + tokenRotatedTag + ` tag,
+so that 'task source show' can tell when a token given --expires-in expires.
+
+Examples:
+  solstice task source rotate --rg mygroup --n myregistry mytask --token-file ~/.tokens/github
+  cat token | solstice task source rotate --rg mygroup --n myregistry --all \
+    --repo-url https://github.com/org/repo --token-file - --expires-in 2160h
+`
+
+type taskSourceRotateCmd struct {
+	resourceGroupName string
+	registryName      string
+	names             []string
+	all               bool
+	repositoryURL     string
+	tokenFile         string
+	tokenType         string
+	refreshTokenFile  string
+	scope             string
+	expiresIn         time.Duration
+	in                io.Reader
+	out               io.Writer
+}
+
+func newTaskSourceRotateCmd(out io.Writer) *cobra.Command {
+	rotateCmd := &taskSourceRotateCmd{
+		in:  os.Stdin,
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "rotate [NAME...]",
+		Short: "Rotate the source control token of build tasks",
+		Long:  taskSourceRotateLongMessage,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rotateCmd.names = args
+			return rotateCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&rotateCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&rotateCmd.registryName, "n", "", "The name of the registry")
+	f.BoolVar(&rotateCmd.all, "all", false, "Rotate the token of every build task")
+	f.StringVar(&rotateCmd.repositoryURL, "repo-url", "", "With --all, only rotate the token of build tasks using this repository")
+	f.StringVar(&rotateCmd.tokenFile, "token-file", "", "A file containing the new token, or '-' to read it from stdin")
+	f.StringVar(&rotateCmd.tokenType, "token-type", string(containerregistry.PAT), "The type of the token: PAT or OAuth")
+	f.StringVar(&rotateCmd.refreshTokenFile, "refresh-token-file", "", "A file containing an OAuth refresh token")
+	f.StringVar(&rotateCmd.scope, "scope", "", "The scope of the token")
+	f.DurationVar(&rotateCmd.expiresIn, "expires-in", 0, "How long the new token is valid for, e.g. 2160h")
+	markStdinFlags(f, "token-file", "refresh-token-file")
+
+	return cmd
+}
+
+func (c *taskSourceRotateCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	auth, err := c.newAuthInfo()
+	if err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	names := c.names
+	if c.all {
+		if names, err = c.findTasks(ctx, tc); err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			fmt.Fprintln(os.Stderr, "No matching build tasks.")
+			return nil
+		}
+	}
+
+	params := containerregistry.BuildTaskUpdateParameters{
+		BuildTaskPropertiesUpdateParameters: &containerregistry.BuildTaskPropertiesUpdateParameters{
+			SourceRepository: &containerregistry.SourceRepositoryUpdateParameters{
+				SourceControlAuthProperties: auth,
+			},
+		},
+	}
+
+	var (
+		results view.TokenRotationList
+		failed  int
+	)
+	for _, name := range names {
+		result := c.rotate(ctx, tc, name, params)
+		if result.Error != "" {
+			failed++
+		}
+		results.Items = append(results.Items, result)
+	}
+
+	err = p.Print(c.out, results, func(w io.Writer) error {
+		for _, r := range results.Items {
+			if r.Result == rotationDone {
+				fmt.Fprintf(w, "Rotated the source control token of build task %s.\n", r.BuildTask)
+			} else {
+				fmt.Fprintf(w, "Failed to rotate the source control token of build task %s: %s\n", r.BuildTask, r.Error)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d build task(s) could not be updated", failed, len(names))
+	}
+	return nil
+}
+
+// newAuthInfo validates the flags and reads the new token.
+func (c *taskSourceRotateCmd) newAuthInfo() (*containerregistry.SourceControlAuthInfo, error) {
+	if err := validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return nil, err
+	}
+	if c.all && len(c.names) > 0 {
+		return nil, errors.New("build task names can't be combined with --all")
+	}
+	if !c.all && len(c.names) == 0 {
+		return nil, errors.New("specify the build tasks to update, or use --all")
+	}
+	if c.repositoryURL != "" && !c.all {
+		return nil, errors.New("--repo-url can only be used with --all")
+	}
+	if c.tokenFile == "" {
+		return nil, errors.New("the new token must be specified with --token-file")
+	}
+	if c.expiresIn < 0 || c.expiresIn/time.Second > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid --expires-in %s: it must be a positive duration of at most %d seconds", c.expiresIn, math.MaxInt32)
+	}
+
+	tokenType, err := parseTokenType(c.tokenType)
+	if err != nil {
+		return nil, err
+	}
+	token, err := readSecretFile(c.tokenFile, c.in)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &containerregistry.SourceControlAuthInfo{
+		TokenType: tokenType,
+		Token:     to.StringPtr(token),
+	}
+	if c.refreshTokenFile != "" {
+		refreshToken, err := readSecretFile(c.refreshTokenFile, c.in)
+		if err != nil {
+			return nil, err
+		}
+		auth.RefreshToken = to.StringPtr(refreshToken)
+	}
+	if c.scope != "" {
+		auth.Scope = to.StringPtr(c.scope)
+	}
+	if c.expiresIn > 0 {
+		auth.ExpiresIn = to.Int32Ptr(int32(c.expiresIn / time.Second))
+	}
+	return auth, nil
+}
+
+// findTasks returns the names of the build tasks selected with --all and --repo-url.
+func (c *taskSourceRotateCmd) findTasks(ctx context.Context, tc containerregistry.BuildTasksClient) ([]string, error) {
+	tasks, err := listTasks(ctx, tc, c.resourceGroupName, c.registryName)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, t := range view.NewBuildTaskList(tasks).Items {
+		if c.repositoryURL != "" {
+			if t.SourceRepository == nil || !sameRepositoryURL(t.SourceRepository.RepositoryURL, c.repositoryURL) {
+				continue
+			}
+		}
+		names = append(names, t.Name)
+	}
+	return names, nil
+}
+
+func (c *taskSourceRotateCmd) rotate(ctx context.Context, tc containerregistry.BuildTasksClient, name string, params containerregistry.BuildTaskUpdateParameters) view.TokenRotation {
+	result := view.TokenRotation{BuildTask: name, Result: rotationFailed}
+
+	// Record when the token was rotated, so that its expiry can be worked out
+	// from its lifetime. The tags are replaced as a whole, so keep the others.
+	current, err := tc.Get(ctx, c.resourceGroupName, c.registryName, name)
+	if err != nil {
+		result.Error = wrapAPIError("Errored while getting the build task", err).Error()
+		return result
+	}
+	params.Tags = make(map[string]*string, len(current.Tags)+1)
+	for k, v := range current.Tags {
+		params.Tags[k] = v
+	}
+	params.Tags[tokenRotatedTag] = to.StringPtr(time.Now().UTC().Format(time.RFC3339))
+
+	fmt.Fprintf(os.Stderr, "Updating build task %s...\n", name)
+	future, err := tc.Update(ctx, c.resourceGroupName, c.registryName, name, params)
+	if err != nil {
+		result.Error = wrapAPIError("Errored while updating the build task", err).Error()
+		return result
+	}
+	if err = future.WaitForCompletion(ctx, tc.Client); err != nil {
+		result.Error = wrapAPIError("Errored while waiting for the build task to be updated", err).Error()
+		return result
+	}
+	if _, err = future.Result(tc); err != nil {
+		result.Error = wrapAPIError("Errored while getting the updated build task", err).Error()
+		return result
+	}
+	result.Result = rotationDone
+	return result
+}
+
+// sameRepositoryURL compares repository URLs, ignoring case, a trailing slash and a ".git" suffix.
+func sameRepositoryURL(a, b string) bool {
+	normalize := func(u string) string {
+		u = strings.ToLower(strings.TrimSuffix(u, "/"))
+		return strings.TrimSuffix(u, ".git")
+	}
+	return normalize(a) == normalize(b)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const taskSourceShowLongMessage = `
+Show the source repository and source control credentials of build tasks.
+
+The type, scope, lifetime and remaining validity of each token are shown, but
+never the token itself. The remaining validity is only known for tokens rotated
+with 'solstice task source rotate --expires-in'. Without build task names,
+every build task of the registry is shown.
+
+Examples:
+  solstice task source show --rg mygroup --n myregistry mytask
+  solstice task source show --rg mygroup --n myregistry --warn-within 720h
+`
+
+type taskSourceShowCmd struct {
+	resourceGroupName string
+	registryName      string
+	names             []string
+	warnWithin        time.Duration
+	out               io.Writer
+}
+
+func newTaskSourceShowCmd(out io.Writer) *cobra.Command {
+	showCmd := &taskSourceShowCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "show [NAME...]",
+		Short: "Show the source control credentials of build tasks",
+		Long:  taskSourceShowLongMessage,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			showCmd.names = args
+			return showCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&showCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&showCmd.registryName, "n", "", "The name of the registry")
+	f.DurationVar(&showCmd.warnWithin, "warn-within", defaultTokenExpiryWarning, "Warn about tokens expiring within this duration")
+
+	return cmd
+}
+
+func (c *taskSourceShowCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	if err = validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*5)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	// The tasks carry the time their token was rotated in their tags.
+	var tasks []containerregistry.BuildTask
+	if len(c.names) == 0 {
+		if tasks, err = listTasks(ctx, tc, c.resourceGroupName, c.registryName); err != nil {
+			return err
+		}
+		sort.Slice(tasks, func(i, j int) bool { return to.String(tasks[i].Name) < to.String(tasks[j].Name) })
+	}
+	for _, name := range c.names {
+		task, err := tc.Get(ctx, c.resourceGroupName, c.registryName, name)
+		if err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while getting build task %s", name), err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	var list view.TaskSourceList
+	for _, task := range tasks {
+		name := to.String(task.Name)
+		props, err := tc.ListSourceRepositoryProperties(ctx, c.resourceGroupName, c.registryName, name)
+		if err != nil {
+			return wrapAPIError(fmt.Sprintf("Errored while getting the source repository of build task %s", name), err)
+		}
+		list.Items = append(list.Items, view.NewTaskSource(name, props, tokenRotatedAt(task.Tags)))
+	}
+
+	now := time.Now()
+	warnExpiringTokens(list.Items, c.warnWithin, now)
+	return p.Print(c.out, list, func(w io.Writer) error {
+		if len(list.Items) == 0 {
+			fmt.Fprintln(os.Stderr, "No build tasks found.")
+			return nil
+		}
+		return printTaskSourceTable(w, list.Items, p.Wide(), now)
+	})
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ehotinger/solstice/client"
+	"github.com/spf13/cobra"
+)
+
+const taskUpdateLongMessage = `
+Update a build task.
+
+Only the properties given as flags are changed. Tags given with --tag replace
+all of the task's existing tags.
+
+Examples:
+  solstice task update --rg mygroup --n myregistry mytask --status Disabled
+  solstice task update --rg mygroup --n myregistry mytask --cpu 4 --timeout 7200
+`
+
+type taskUpdateCmd struct {
+	resourceGroupName string
+	registryName      string
+	name              string
+	alias             string
+	status            string
+	commitTrigger     bool
+	osType            string
+	cpu               int32
+	timeout           int32
+	tags              []string
+	changed           func(flag string) bool
+	out               io.Writer
+}
+
+func newTaskUpdateCmd(out io.Writer) *cobra.Command {
+	updateCmd := &taskUpdateCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "update NAME",
+		Short: "Update a build task",
+		Long:  taskUpdateLongMessage,
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			updateCmd.name = args[0]
+			updateCmd.changed = cmd.Flags().Changed
+			return updateCmd.run()
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&updateCmd.resourceGroupName, "rg", "", "The resource group to use for auth")
+	f.StringVar(&updateCmd.registryName, "n", "", "The name of the registry")
+	f.StringVar(&updateCmd.alias, "alias", "", "The alias of the build task")
+	f.StringVar(&updateCmd.status, "status", "", "The status of the build task: Enabled or Disabled")
+	f.BoolVar(&updateCmd.commitTrigger, "commit-trigger", true, "Build whenever a commit is pushed to the repository")
+	f.StringVar(&updateCmd.osType, "os", "", "The operating system to build on: linux or windows")
+	f.Int32Var(&updateCmd.cpu, "cpu", 0, "The number of CPU cores to build with")
+	f.Int32Var(&updateCmd.timeout, "timeout", 0, fmt.Sprintf("The build timeout in seconds (%d-%d)", minTimeout, maxTimeout))
+	f.StringArrayVar(&updateCmd.tags, "tag", nil, "A tag in KEY=VALUE form (repeatable)")
+
+	return cmd
+}
+
+func (c *taskUpdateCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+	params, err := c.newUpdateParameters()
+	if err != nil {
+		return validationError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancel()
+
+	subscription, err := getSubscription()
+	if err != nil {
+		return subscriptionError(err)
+	}
+
+	tc, err := client.GetBuildTasksClient(subscription.ID)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while creating client. Err: %v", err))
+	}
+
+	// The platform is replaced as a whole, so keep the current OS when only the
+	// CPU count changes, and the current CPU count when only the OS changes.
+	// Tags are replaced as a whole too, but the one owned by solstice is kept.
+	if c.changed("cpu") != c.changed("os") || c.changed("tag") {
+		current, err := tc.Get(ctx, c.resourceGroupName, c.registryName, c.name)
+		if err != nil {
+			return wrapAPIError("Errored while getting the build task", err)
+		}
+		params.Tags = keepTokenRotatedTag(params.Tags, current.Tags)
+		if params.Platform != nil && current.BuildTaskProperties != nil && current.Platform != nil {
+			if !c.changed("os") {
+				params.Platform.OsType = current.Platform.OsType
+			}
+			if !c.changed("cpu") {
+				params.Platform.CPU = current.Platform.CPU
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Updating build task %s...\n", c.name)
+	future, err := tc.Update(ctx, c.resourceGroupName, c.registryName, c.name, params)
+	if err != nil {
+		return wrapAPIError("Errored while updating the build task", err)
+	}
+	if err = future.WaitForCompletion(ctx, tc.Client); err != nil {
+		return wrapAPIError("Errored while waiting for the build task to be updated", err)
+	}
+	updated, err := future.Result(tc)
+	if err != nil {
+		return wrapAPIError("Errored while getting the updated build task", err)
+	}
+
+	return printTask(p, c.out, updated)
+}
+
+// newUpdateParameters validates the changed flags and maps them onto BuildTaskUpdateParameters.
+func (c *taskUpdateCmd) newUpdateParameters() (containerregistry.BuildTaskUpdateParameters, error) {
+	props := &containerregistry.BuildTaskPropertiesUpdateParameters{}
+	params := containerregistry.BuildTaskUpdateParameters{
+		BuildTaskPropertiesUpdateParameters: props,
+	}
+	if err := validateRegistry(c.resourceGroupName, c.registryName); err != nil {
+		return params, err
+	}
+
+	updated := false
+	if c.changed("alias") {
+		if c.alias == "" {
+			return params, errors.New("--alias must not be empty")
+		}
+		props.Alias = to.StringPtr(c.alias)
+		updated = true
+	}
+	if c.changed("status") {
+		status, err := parseTaskStatus(c.status)
+		if err != nil {
+			return params, err
+		}
+		props.Status = status
+		updated = true
+	}
+	if c.changed("commit-trigger") {
+		props.SourceRepository = &containerregistry.SourceRepositoryUpdateParameters{
+			IsCommitTriggerEnabled: to.BoolPtr(c.commitTrigger),
+		}
+		updated = true
+	}
+	if c.changed("os") || c.changed("cpu") {
+		if c.changed("cpu") && c.cpu <= 0 {
+			return params, fmt.Errorf("invalid cpu count %d: it must be a positive number", c.cpu)
+		}
+		osType := c.osType
+		if !c.changed("os") {
+			// Replaced with the task's current OS once it has been fetched.
+			osType = "linux"
+		}
+		platform, err := parsePlatform(osType, c.cpu)
+		if err != nil {
+			return params, err
+		}
+		props.Platform = platform
+		updated = true
+	}
+	if c.changed("timeout") {
+		if err := validateTimeout(c.timeout); err != nil {
+			return params, err
+		}
+		props.Timeout = to.Int32Ptr(c.timeout)
+		updated = true
+	}
+	if c.changed("tag") {
+		tags, err := parseTags(c.tags)
+		if err != nil {
+			return params, err
+		}
+		params.Tags = tags
+		updated = true
+	}
+
+	if !updated {
+		return params, errors.New("nothing to update, specify at least one property to change")
+	}
+	return params, nil
+}
@@ -12,19 +12,39 @@ const versionLongMessage = `
 Shows the version of Solstice.
 `
 
+// versionInfo is the structured form of the version information.
+type versionInfo struct {
+	GoVersion  string `json:"goVersion"`
+	GoCompiler string `json:"goCompiler"`
+	Platform   string `json:"platform"`
+}
+
 func newVersionCmd(out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the version information",
 		Long:  versionLongMessage,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Printf(`go version  : %s
+			p, err := settings.newPrinter()
+			if err != nil {
+				return err
+			}
+
+			v := versionInfo{
+				GoVersion:  runtime.Version(),
+				GoCompiler: runtime.Compiler,
+				Platform:   fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
+			}
+			return p.Print(out, v, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, `go version  : %s
 go compiler : %s
-platform    : %s/%s
-`, runtime.Version(), runtime.Compiler, runtime.GOOS, runtime.GOARCH)
-			return nil
+platform    : %s
+`, v.GoVersion, v.GoCompiler, v.Platform)
+				return err
+			})
 		},
 	}
+	markOffline(cmd)
 
 	return cmd
 }
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ehotinger/solstice/iam"
+	"github.com/ehotinger/solstice/pkg/view"
+	"github.com/spf13/cobra"
+)
+
+const whoamiLongMessage = `
+Show the identity solstice authenticates as, to diagnose authentication
+failures.
+
+The access token selected for the cloud and subscription in use is decoded to
+show the user or application, its tenant, audience and expiry, together with
+the grant type and where the token came from. The token itself is never shown.
+
+Examples:
+  solstice whoami
+  solstice whoami --subscription Production -o json
+`
+
+type whoamiCmd struct {
+	out io.Writer
+}
+
+func newWhoamiCmd(out io.Writer) *cobra.Command {
+	whoamiCmd := &whoamiCmd{
+		out: out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the identity used to authenticate",
+		Long:  whoamiLongMessage,
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return whoamiCmd.run()
+		},
+	}
+
+	return cmd
+}
+
+func (c *whoamiCmd) run() error {
+	p, err := settings.newPrinter()
+	if err != nil {
+		return err
+	}
+
+	id := view.Identity{
+		Cloud: iam.Environment().Name,
+	}
+	tenant := ""
+	subscription, err := getSubscription()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: no subscription is selected: %v\n", err)
+	} else {
+		id.Subscription = &view.Subscription{
+			ID:        subscription.ID,
+			Name:      subscription.Name,
+			State:     subscription.State,
+			IsDefault: subscription.IsDefault,
+		}
+		tenant = iam.SubscriptionTenant(subscription.ID)
+	}
+
+	grantType := iam.AuthGrantType()
+	info, err := iam.ResourceManagementTokenInfo(grantType, tenant)
+	if err != nil {
+		return authError(fmt.Errorf("Errored while getting a token with grant type %s. Err: %v", grantType, err))
+	}
+	id.GrantType = info.GrantType.String()
+	id.Source = info.Source
+	id.User = info.User
+	id.AppID = info.AppID
+	id.ObjectID = info.ObjectID
+	id.TenantID = info.TenantID
+	id.Audience = info.Audience
+	if !info.ExpiresOn.IsZero() {
+		id.ExpiresOn = &info.ExpiresOn
+	}
+
+	return p.Print(c.out, id, func(out io.Writer) error {
+		w := tabwriter.NewWriter(out, 0, 8, 1, ' ', 0)
+		fmt.Fprintf(w, "Cloud:\t%s\n", id.Cloud)
+		if id.Subscription != nil {
+			fmt.Fprintf(w, "Subscription:\t%s\n", describeSubscription(id.Subscription))
+		} else {
+			fmt.Fprintf(w, "Subscription:\t<none>\n")
+		}
+		fmt.Fprintf(w, "Grant type:\t%s\n", id.GrantType)
+		fmt.Fprintf(w, "Source:\t%s\n", id.Source)
+		if id.User != "" {
+			fmt.Fprintf(w, "User:\t%s\n", id.User)
+		}
+		if id.AppID != "" {
+			fmt.Fprintf(w, "App ID:\t%s\n", id.AppID)
+		}
+		if id.ObjectID != "" {
+			fmt.Fprintf(w, "Object ID:\t%s\n", id.ObjectID)
+		}
+		fmt.Fprintf(w, "Tenant:\t%s\n", id.TenantID)
+		fmt.Fprintf(w, "Audience:\t%s\n", id.Audience)
+		if id.ExpiresOn != nil {
+			fmt.Fprintf(w, "Expires:\t%s (in %s)\n", id.ExpiresOn.Local().Format(time.RFC3339), time.Until(*id.ExpiresOn).Round(time.Minute))
+		}
+		return w.Flush()
+	})
+}
+
+func describeSubscription(sub *view.Subscription) string {
+	if sub.Name == "" {
+		return sub.ID
+	}
+	return fmt.Sprintf("%s (%s)", sub.Name, sub.ID)
+}
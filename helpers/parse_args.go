@@ -8,7 +8,6 @@ package helpers
 import (
 	"errors"
 	"flag"
-	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -59,7 +58,6 @@ var (
 // ParseArgs picks up shared env vars and flags and finishes parsing flags
 // Other packages should declare their flags then call helpers.ParseArgs()
 func ParseArgs() error {
-	fmt.Println("parse args")
 	err := ParseSubscriptionID()
 	if err != nil {
 		return err
@@ -110,7 +108,6 @@ func ParseArgs() error {
 // ParseSubscriptionID gets the subscription id from either an env var, .env file or flag
 // The caller should do flag.Parse()
 func ParseSubscriptionID() error {
-	fmt.Println("parse sub")
 	err := ReadEnvFile()
 	if err != nil {
 		return err
@@ -218,10 +215,9 @@ func OverrideLocation(available []string) {
 
 // ReadEnvFile reads the .env file and loads its environment variables.
 func ReadEnvFile() error {
-	fmt.Println("reading env file")
 	// err := gotenv.Load() // to allow use of .env file
 	// if err != nil && !strings.HasPrefix(err.Error(), "open .env:") {
-	// 	return errk
+	// 	return err
 	// }
 	return nil
 }
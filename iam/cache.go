@@ -0,0 +1,144 @@
+package iam
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+// refreshWithin is how long before they expire tokens are refreshed, so that
+// requests made by long running commands never carry a token about to expire.
+const refreshWithin = 10 * time.Minute
+
+// authorizers caches the tokens of every authorizer handed out by this package.
+var authorizers = newAuthorizerCache()
+
+// authorizerKey identifies the token of an authorizer. The cloud is identified
+// by its Active Directory endpoint.
+type authorizerKey struct {
+	cloud     string
+	tenant    string
+	resource  string
+	grantType OAuthGrantType
+}
+
+// newAuthorizerKey builds the key of the token for a resource of a tenant in
+// the selected cloud. Managed identities only have a token per resource.
+func newAuthorizerKey(grantType OAuthGrantType, tenant, resource string) authorizerKey {
+	if grantType == OAuthGrantTypeManagedIdentity {
+		tenant = ""
+	}
+	return authorizerKey{
+		cloud:     environment.ActiveDirectoryEndpoint,
+		tenant:    tenant,
+		resource:  resource,
+		grantType: grantType,
+	}
+}
+
+// authorizerCache holds a token per key, which is acquired once per process and
+// shared by every authorizer for the key. It's safe for concurrent use; tokens
+// for different keys are acquired and refreshed concurrently.
+type authorizerCache struct {
+	mu      sync.Mutex
+	entries map[authorizerKey]*authorizerEntry
+}
+
+func newAuthorizerCache() *authorizerCache {
+	return &authorizerCache{entries: map[authorizerKey]*authorizerEntry{}}
+}
+
+// entry returns the entry for a key, creating an empty one if needed.
+func (c *authorizerCache) entry(key authorizerKey) *authorizerEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &authorizerEntry{key: key}
+		c.entries[key] = e
+	}
+	return e
+}
+
+// authorizer returns an authorizer using the token for a key, getting the token
+// first if it isn't cached yet.
+func (c *authorizerCache) authorizer(key authorizerKey) (autorest.Authorizer, error) {
+	e := c.entry(key)
+	if _, err := e.tokenProvider(false); err != nil {
+		return nil, err
+	}
+	return &cachedAuthorizer{entry: e}, nil
+}
+
+// authorizerEntry holds the token for a key.
+type authorizerEntry struct {
+	key      authorizerKey
+	mu       sync.Mutex
+	provider adal.OAuthTokenProvider
+}
+
+// tokenProvider returns the entry's token, getting it if it isn't cached yet.
+// When fresh is set, the token is refreshed if it expires within refreshWithin.
+// Tokens which can't be refreshed, such as those of the az CLI without a
+// refresh token, are read again instead, since the az CLI may have renewed them.
+func (e *authorizerEntry) tokenProvider(fresh bool) (adal.OAuthTokenProvider, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if t, ok := e.provider.(*adal.Token); ok && fresh && t.WillExpireIn(refreshWithin) {
+		p, err := getTokenProvider(e.key.grantType, e.key.tenant, e.key.resource)
+		if err == nil {
+			e.provider = p
+		} else if t.IsExpired() {
+			return nil, err
+		}
+	}
+	if e.provider == nil {
+		p, err := getTokenProvider(e.key.grantType, e.key.tenant, e.key.resource)
+		if err != nil {
+			return nil, err
+		}
+		e.provider = p
+	}
+	if spt, ok := e.provider.(*adal.ServicePrincipalToken); ok {
+		spt.SetRefreshWithin(refreshWithin)
+	}
+	if r, ok := e.provider.(adal.Refresher); ok && fresh {
+		if err := r.EnsureFresh(); err != nil {
+			return nil, err
+		}
+	}
+	return e.provider, nil
+}
+
+// cachedAuthorizer adds the token of a cache entry to requests, refreshing it
+// ahead of its expiry.
+type cachedAuthorizer struct {
+	entry *authorizerEntry
+}
+
+// WithAuthorization returns a PrepareDecorator which adds the entry's token as
+// a bearer token.
+func (a *cachedAuthorizer) WithAuthorization() autorest.PrepareDecorator {
+	return func(p autorest.Preparer) autorest.Preparer {
+		return autorest.PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			r, err := p.Prepare(r)
+			if err != nil {
+				return r, err
+			}
+			provider, err := a.entry.tokenProvider(true)
+			if err != nil {
+				var resp *http.Response
+				if tokError, ok := err.(adal.TokenRefreshError); ok {
+					resp = tokError.Response()
+				}
+				return r, autorest.NewErrorWithError(err, "iam.cachedAuthorizer", "WithAuthorization", resp,
+					"Failed to refresh the token for request to %s", r.URL)
+			}
+			return autorest.Prepare(r, autorest.WithBearerAuthorization(provider.OAuthToken()))
+		})
+	}
+}
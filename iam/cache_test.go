@@ -0,0 +1,120 @@
+package iam
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/ehotinger/solstice/iam/msitest"
+)
+
+func TestAuthorizerCacheConcurrent(t *testing.T) {
+	srv := msitest.NewServer("msi-token")
+	defer srv.Close()
+	defer useManagedIdentity(srv, "")()
+
+	resources := []string{"https://management.azure.com/", "https://vault.azure.net", "https://graph.windows.net/"}
+	const callers = 16
+
+	var wg sync.WaitGroup
+	for _, resource := range resources {
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(resource string, i int) {
+				defer wg.Done()
+				// Managed identities ignore the tenant, so these share a token.
+				key := newAuthorizerKey(OAuthGrantTypeManagedIdentity, fmt.Sprintf("tenant-%d", i%2), resource)
+				a, err := authorizers.authorizer(key)
+				if err != nil {
+					t.Errorf("failed to get an authorizer for %s: %v", resource, err)
+					return
+				}
+				r, err := http.NewRequest(http.MethodGet, resource, nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if r, err = autorest.Prepare(r, a.WithAuthorization()); err != nil {
+					t.Errorf("failed to authorize a request to %s: %v", resource, err)
+					return
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer msi-token" {
+					t.Errorf("Authorization = %q, want the managed identity's token", got)
+				}
+			}(resource, i)
+		}
+	}
+	wg.Wait()
+
+	requested := map[string]int{}
+	for _, r := range srv.Requests() {
+		requested[r.Resource]++
+	}
+	for _, resource := range resources {
+		if requested[resource] != 1 {
+			t.Errorf("got %d token requests for %s, want 1", requested[resource], resource)
+		}
+	}
+	if len(requested) != len(resources) {
+		t.Errorf("tokens were requested for %v, want only %v", requested, resources)
+	}
+}
+
+func TestAuthorizerRefreshesExpiringTokens(t *testing.T) {
+	tests := []struct {
+		name         string
+		expiresIn    time.Duration
+		wantRequests int
+	}{
+		{"valid for longer", time.Hour, 1},
+		// Expiring within refreshWithin, the token is refreshed for every request.
+		{"about to expire", refreshWithin / 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := msitest.NewServerExpiringIn("msi-token", tt.expiresIn)
+			defer srv.Close()
+			defer useManagedIdentity(srv, "")()
+
+			a, err := GetResourceManagementAuthorizer(OAuthGrantTypeManagedIdentity)
+			if err != nil {
+				t.Fatalf("failed to get an authorizer: %v", err)
+			}
+			for i := 0; i < 3; i++ {
+				if got := bearerToken(t, a); got != "Bearer msi-token" {
+					t.Errorf("Authorization = %q, want the managed identity's token", got)
+				}
+			}
+			if n := len(srv.Requests()); n != tt.wantRequests {
+				t.Errorf("got %d token requests after three requests, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestAuthGrantTypeConcurrent(t *testing.T) {
+	oldMode, oldLoggedIn := authMode, loggedIn
+	defer setAuthMode(oldMode, oldLoggedIn)
+	setAuthMode(nil, false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			useLogin()
+		}()
+		go func() {
+			defer wg.Done()
+			AuthGrantType()
+		}()
+	}
+	wg.Wait()
+
+	if g := AuthGrantType(); g != OAuthGrantTypeLogin {
+		t.Errorf("AuthGrantType() = %v after signing in, want login", g)
+	}
+}
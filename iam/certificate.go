@@ -0,0 +1,108 @@
+package iam
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// loadCertificate reads a client certificate and its RSA private key from a
+// PEM file, or from a PFX (PKCS#12) file protected by password.
+func loadCertificate(path, password string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the certificate: %v", err)
+	}
+
+	var (
+		certificate *x509.Certificate
+		privateKey  *rsa.PrivateKey
+	)
+	if bytes.Contains(data, []byte("-----BEGIN")) {
+		certificate, privateKey, err = decodePEM(data)
+	} else {
+		certificate, privateKey, err = decodePFX(data, password)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load the certificate from %s: %v", path, err)
+	}
+	return certificate, privateKey, nil
+}
+
+func decodePFX(data []byte, password string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	key, certificate, err := pkcs12.Decode(data, password)
+	if _, ok := err.(pkcs12.NotImplementedError); ok {
+		return nil, nil, fmt.Errorf("%v; only PFX files encrypted with SHA-1 and 3DES are supported, convert the file to PEM otherwise", err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("the private key must be an RSA key")
+	}
+	return certificate, privateKey, nil
+}
+
+// decodePEM reads the first certificate and the first private key of a PEM file.
+func decodePEM(data []byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+	var (
+		certificate *x509.Certificate
+		privateKey  *rsa.PrivateKey
+	)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+			if certificate != nil {
+				continue
+			}
+			c, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			certificate = c
+		case "RSA PRIVATE KEY":
+			if privateKey != nil {
+				continue
+			}
+			k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			privateKey = k
+		case "PRIVATE KEY":
+			if privateKey != nil {
+				continue
+			}
+			k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			rsaKey, ok := k.(*rsa.PrivateKey)
+			if !ok {
+				return nil, nil, errors.New("the private key must be an RSA key")
+			}
+			privateKey = rsaKey
+		}
+	}
+
+	if certificate == nil {
+		return nil, nil, errors.New("no certificate found")
+	}
+	if privateKey == nil {
+		return nil, nil, errors.New("no private key found")
+	}
+	return certificate, privateKey, nil
+}
@@ -0,0 +1,136 @@
+package iam
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testdata/certificate.pfx holds a self-signed certificate for CN=solstice-test
+// and its RSA key, encrypted with SHA-1 and 3DES and protected by this password.
+// testdata/certificate-aes.pfx holds the same, encrypted with AES.
+const testPFXPassword = "solstice"
+
+func newTestCertificate(t *testing.T) ([]byte, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "solstice-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der, key
+}
+
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCertificatePEM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solstice-certificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	der, key := newTestCertificate(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	pkcs1Key := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	pkcs8Key := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"PKCS#1 key", concat(certificate, pkcs1Key), ""},
+		{"PKCS#8 key", concat(certificate, pkcs8Key), ""},
+		{"key first", concat(pkcs8Key, certificate), ""},
+		{"no key", certificate, "no private key found"},
+		{"no certificate", pkcs1Key, "no certificate found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, dir, "certificate.pem", tt.data)
+			c, k, err := loadCertificate(path, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadCertificate errored with %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadCertificate errored: %v", err)
+			}
+			if c.Subject.CommonName != "solstice-test" {
+				t.Errorf("the certificate's subject is %s", c.Subject)
+			}
+			if k.N.Cmp(key.N) != 0 {
+				t.Error("the private key doesn't match")
+			}
+		})
+	}
+}
+
+func TestLoadCertificatePFX(t *testing.T) {
+	path := filepath.Join("testdata", "certificate.pfx")
+	c, k, err := loadCertificate(path, testPFXPassword)
+	if err != nil {
+		t.Fatalf("loadCertificate errored: %v", err)
+	}
+	if c.Subject.CommonName != "solstice-test" {
+		t.Errorf("the certificate's subject is %s", c.Subject)
+	}
+	if pub, ok := c.PublicKey.(*rsa.PublicKey); !ok || pub.N.Cmp(k.N) != 0 {
+		t.Error("the private key doesn't match the certificate")
+	}
+
+	if _, _, err = loadCertificate(path, "wrong"); err == nil {
+		t.Error("loadCertificate with the wrong password didn't error")
+	}
+
+	_, _, err = loadCertificate(filepath.Join("testdata", "certificate-aes.pfx"), testPFXPassword)
+	if err == nil || !strings.Contains(err.Error(), "convert the file to PEM") {
+		t.Errorf("loadCertificate of an AES encrypted PFX file errored with %v, want a hint to convert it", err)
+	}
+}
+
+func TestLoadCertificateMissingFile(t *testing.T) {
+	if _, _, err := loadCertificate(filepath.Join("testdata", "missing.pem"), ""); err == nil {
+		t.Error("loadCertificate of a missing file didn't error")
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
@@ -0,0 +1,27 @@
+package iam
+
+import (
+	"fmt"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// tokenClaims decodes the claims of an access token. The signature isn't
+// verified: the claims only describe the token to its holder.
+func tokenClaims(accessToken string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(accessToken, claims); err != nil {
+		return nil, fmt.Errorf("failed to decode the access token: %v", err)
+	}
+	return claims, nil
+}
+
+// claimString returns the first of the given claims which is a non-empty string.
+func claimString(claims jwt.MapClaims, names ...string) string {
+	for _, name := range names {
+		if s, ok := claims[name].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,235 @@
+package iam
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure/cli"
+)
+
+// cliExpiresOnFormat is the format of the expiresOn field written by the az CLI, in local time.
+const cliExpiresOnFormat = "2006-01-02 15:04:05.000000"
+
+// accessTokensLock serializes the updates of the az CLI token cache made by this process.
+var accessTokensLock sync.Mutex
+
+// getCLIToken returns a token cached by `az login` for the management endpoint
+// and the given tenant. Expired tokens are refreshed with their refresh token,
+// and the refreshed tokens are written back to the cache.
+func getCLIToken(tenant string) (adal.OAuthTokenProvider, error) {
+	tokenPath, err := cli.AccessTokensPath()
+	if err != nil {
+		return nil, fmt.Errorf("There was an error while grabbing the access token path: %v", err)
+	}
+	tokens, err := cli.LoadTokens(tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("There was an error loading the tokens from %s, run `az login` or configure a service principal: %v", tokenPath, err)
+	}
+
+	token, adalToken, ok := selectCLIToken(tokens, tenant)
+	if !ok {
+		if tenant != "" {
+			return nil, fmt.Errorf("no usable token for tenant %s in %s, run `az login --tenant %s`", tenant, tokenPath, tenant)
+		}
+		return nil, fmt.Errorf("run `az login` to get started, or set AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET to use a service principal")
+	}
+	if token.RefreshToken == "" {
+		return &adalToken, nil
+	}
+
+	adEndpoint, tokenTenant, err := parseAuthority(token.Authority)
+	if err != nil {
+		return nil, err
+	}
+	config, err := adal.NewOAuthConfig(adEndpoint, tokenTenant)
+	if err != nil {
+		return nil, err
+	}
+	spt, err := adal.NewServicePrincipalTokenFromManualToken(*config, token.ClientID, token.Resource, adalToken, func(refreshed adal.Token) error {
+		return saveCLIToken(tokenPath, token, refreshed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spt, nil
+}
+
+// selectCLIToken picks the cached token for the management endpoint and the
+// tenant which expires last. Expired tokens are only picked if they can be refreshed.
+func selectCLIToken(tokens []cli.Token, tenant string) (cli.Token, adal.Token, bool) {
+	var (
+		best      cli.Token
+		bestADAL  adal.Token
+		bestUntil time.Time
+		found     bool
+	)
+	for _, token := range tokens {
+		if !isManagementResource(token.Resource) {
+			continue
+		}
+		if tenant != "" {
+			if _, t, err := parseAuthority(token.Authority); err != nil || !strings.EqualFold(t, tenant) {
+				continue
+			}
+		}
+		adalToken, err := token.ToADALToken()
+		if err != nil {
+			continue
+		}
+		if adalToken.IsExpired() && token.RefreshToken == "" {
+			continue
+		}
+		expiresOn, err := cli.ParseExpirationDate(token.ExpiresOn)
+		if err != nil {
+			continue
+		}
+		if !found || expiresOn.After(bestUntil) {
+			best, bestADAL, bestUntil, found = token, adalToken, *expiresOn, true
+		}
+	}
+	return best, bestADAL, found
+}
+
+// isManagementResource reports whether a token's resource is the Resource
+// Manager of the selected cloud, which accepts tokens for its own endpoint, its
+// token audience and the classic management endpoint.
+func isManagementResource(resource string) bool {
+	resource = strings.TrimSuffix(resource, "/")
+	for _, r := range []string{environment.ServiceManagementEndpoint, environment.ResourceManagerEndpoint, environment.TokenAudience} {
+		if r != "" && strings.EqualFold(resource, strings.TrimSuffix(r, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAuthority splits an authority such as https://login.microsoftonline.com/<tenant>
+// into the Active Directory endpoint and the tenant.
+func parseAuthority(authority string) (string, string, error) {
+	u, err := url.Parse(authority)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid token authority %q", authority)
+	}
+	tenant := strings.Trim(u.Path, "/")
+	if tenant == "" || strings.Contains(tenant, "/") {
+		return "", "", fmt.Errorf("invalid token authority %q", authority)
+	}
+	return u.Scheme + "://" + u.Host + "/", tenant, nil
+}
+
+// saveCLIToken replaces the cached token which was refreshed. The cache is
+// rewritten as generic JSON, so that fields unknown to cli.Token are kept.
+func saveCLIToken(path string, original cli.Token, refreshed adal.Token) error {
+	accessTokensLock.Lock()
+	defer accessTokensLock.Unlock()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to save the refreshed token: %v", err)
+	}
+	var entries []map[string]interface{}
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to save the refreshed token: %v", err)
+	}
+
+	expiresOn, err := strconv.ParseInt(string(refreshed.ExpiresOn), 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to save the refreshed token: invalid expiry %q", refreshed.ExpiresOn)
+	}
+
+	updated := false
+	for _, e := range entries {
+		if e["_authority"] != original.Authority || e["_clientId"] != original.ClientID ||
+			e["resource"] != original.Resource || e["userId"] != original.UserID {
+			continue
+		}
+		e["accessToken"] = refreshed.AccessToken
+		e["expiresOn"] = time.Unix(expiresOn, 0).Local().Format(cliExpiresOnFormat)
+		if refreshed.ExpiresIn != "" {
+			if n, err := strconv.Atoi(string(refreshed.ExpiresIn)); err == nil {
+				e["expiresIn"] = n
+			}
+		}
+		if refreshed.RefreshToken != "" {
+			e["refreshToken"] = refreshed.RefreshToken
+		}
+		updated = true
+	}
+	if !updated {
+		return nil
+	}
+
+	data, err = json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to save the refreshed token: %v", err)
+	}
+	mode := os.FileMode(0600)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err = writeFileAtomic(path, data, mode); err != nil {
+		return fmt.Errorf("failed to save the refreshed token: %v", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces a file by renaming a temporary file with the given
+// permissions over it, so that readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// SubscriptionTenant returns the tenant of a subscription according to the
+// subscriptions saved by `solstice login` or the az profile, or an empty string
+// if the subscription isn't part of either.
+func SubscriptionTenant(subscriptionID string) string {
+	if subs, err := LoginSubscriptions(); err == nil {
+		for _, sub := range subs {
+			if strings.EqualFold(sub.ID, subscriptionID) {
+				return sub.TenantID
+			}
+		}
+	}
+	profilePath, err := cli.ProfilePath()
+	if err != nil {
+		return ""
+	}
+	profile, err := cli.LoadProfile(profilePath)
+	if err != nil {
+		return ""
+	}
+	for _, sub := range profile.Subscriptions {
+		if strings.EqualFold(sub.ID, subscriptionID) {
+			return sub.TenantID
+		}
+	}
+	return ""
+}
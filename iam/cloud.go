@@ -0,0 +1,96 @@
+package iam
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// environment holds the endpoints of the Azure cloud solstice talks to.
+var environment = azure.PublicCloud
+
+// cloudAliases are short names for the clouds known to autorest.
+var cloudAliases = map[string]string{
+	"public":       "AzurePublicCloud",
+	"china":        "AzureChinaCloud",
+	"usgovernment": "AzureUSGovernmentCloud",
+	"usgov":        "AzureUSGovernmentCloud",
+	"german":       "AzureGermanCloud",
+}
+
+// Clouds describes the values accepted by ParseEnvironment.
+const Clouds = "public, china, usgovernment, german, a full cloud name such as AzureChinaCloud, " +
+	"the path of an environment JSON file, or the https URL of a Resource Manager endpoint"
+
+// ParseEnvironment returns the endpoints of a cloud given by name, by the path of
+// a JSON file describing an azure.Environment, or by the URL of a Resource Manager
+// endpoint, such as Azure Stack's, whose metadata lists the other endpoints.
+func ParseEnvironment(cloud string) (azure.Environment, error) {
+	if cloud == "" {
+		return azure.PublicCloud, nil
+	}
+	if name, ok := cloudAliases[strings.ToLower(cloud)]; ok {
+		cloud = name
+	}
+
+	switch {
+	case strings.HasPrefix(strings.ToLower(cloud), "https://"):
+		env, err := azure.EnvironmentFromURL(cloud)
+		if err != nil {
+			return env, fmt.Errorf("failed to read the cloud's metadata from %s: %v", cloud, err)
+		}
+		return env, nil
+	case IsEnvironmentFile(cloud):
+		env, err := azure.EnvironmentFromFile(cloud)
+		if err != nil {
+			return env, fmt.Errorf("failed to read the cloud environment file %s: %v", cloud, err)
+		}
+		if err = validateEnvironment(env); err != nil {
+			return env, fmt.Errorf("invalid cloud environment file %s: %v", cloud, err)
+		}
+		return env, nil
+	}
+
+	env, err := azure.EnvironmentFromName(cloud)
+	if err != nil {
+		return env, fmt.Errorf("unknown cloud %q: it must be %s", cloud, Clouds)
+	}
+	return env, nil
+}
+
+// IsEnvironmentFile reports whether ParseEnvironment reads a cloud from a file.
+func IsEnvironmentFile(cloud string) bool {
+	return strings.HasSuffix(strings.ToLower(cloud), ".json") || strings.ContainsRune(cloud, os.PathSeparator)
+}
+
+func validateEnvironment(env azure.Environment) error {
+	if env.ResourceManagerEndpoint == "" {
+		return errors.New("resourceManagerEndpoint is required")
+	}
+	if env.ActiveDirectoryEndpoint == "" {
+		return errors.New("activeDirectoryEndpoint is required")
+	}
+	return nil
+}
+
+// SetEnvironment selects the cloud to authenticate against. Authorizers
+// requested afterwards get tokens for the new cloud.
+func SetEnvironment(env azure.Environment) {
+	environment = env
+}
+
+// Environment returns the endpoints of the selected cloud.
+func Environment() azure.Environment {
+	return environment
+}
+
+// managementAudience is the resource tokens for Resource Manager are requested for.
+func managementAudience() string {
+	if environment.TokenAudience != "" {
+		return environment.TokenAudience
+	}
+	return environment.ResourceManagerEndpoint
+}
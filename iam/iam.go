@@ -6,16 +6,14 @@
 package iam
 
 import (
+	"errors"
 	"fmt"
-	"log"
-	"net/url"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
-	"github.com/Azure/go-autorest/autorest/azure"
-	"github.com/Azure/go-autorest/autorest/azure/auth"
-	"github.com/Azure/go-autorest/autorest/azure/cli"
 	"github.com/ehotinger/solstice/helpers"
 )
 
@@ -26,17 +24,25 @@ const (
 
 var (
 	// for service principal and device
-	clientID           string
-	oauthConfig        *adal.OAuthConfig
-	armAuthorizer      autorest.Authorizer
-	batchAuthorizer    autorest.Authorizer
-	graphAuthorizer    autorest.Authorizer
-	keyvaultAuthorizer autorest.Authorizer
+	clientID string
 
 	// for service principal
 	subscriptionID string
 	tenantID       string
-	clientSecret   string
+	clientSecret   = &secret{}
+	// for client certificate
+	certificatePath     string
+	certificatePassword = &secret{}
+	// for managed identity
+	msiEndpoint string
+	// for device flow
+	deviceFlow bool
+	// modeMu guards authMode and loggedIn, which change when signing in or out.
+	modeMu sync.Mutex
+	// authMode is the grant type selected explicitly, if any
+	authMode *OAuthGrantType
+	// loggedIn is set when `solstice login` cached credentials for the cloud
+	loggedIn bool
 	// UseCLIclientID sets if the Azure CLI client iD should be used on device authentication
 	UseCLIclientID bool
 )
@@ -49,14 +55,135 @@ const (
 	OAuthGrantTypeServicePrincipal OAuthGrantType = iota
 	// OAuthGrantTypeDeviceFlow for device-auth flow
 	OAuthGrantTypeDeviceFlow
+	// OAuthGrantTypeCLI for the tokens cached by `az login`
+	OAuthGrantTypeCLI
+	// OAuthGrantTypeClientCertificate for client credentials flow with a certificate
+	OAuthGrantTypeClientCertificate
+	// OAuthGrantTypeManagedIdentity for the managed identity of an Azure VM
+	OAuthGrantTypeManagedIdentity
+	// OAuthGrantTypeLogin for the tokens cached by `solstice login`
+	OAuthGrantTypeLogin
 )
 
-func init() {
-	// fmt.Println("init")
-	// err := parseArgs()
-	// if err != nil {
-	// 	log.Fatalf("failed to parse args: %s\n", err)
-	// }
+// authModes maps the names accepted by --auth-mode to grant types.
+var authModes = []struct {
+	name      string
+	grantType OAuthGrantType
+}{
+	{"login", OAuthGrantTypeLogin},
+	{"cli", OAuthGrantTypeCLI},
+	{"device", OAuthGrantTypeDeviceFlow},
+	{"secret", OAuthGrantTypeServicePrincipal},
+	{"certificate", OAuthGrantTypeClientCertificate},
+	{"msi", OAuthGrantTypeManagedIdentity},
+}
+
+// AuthModes is the list of values accepted by ParseAuthMode.
+const AuthModes = "auto, login, cli, device, secret, certificate or msi"
+
+// ParseAuthMode converts the name of an auth mode into a grant type. "auto"
+// and the empty string return nil, leaving the choice to AuthGrantType.
+func ParseAuthMode(s string) (*OAuthGrantType, error) {
+	if s == "" || strings.EqualFold(s, "auto") {
+		return nil, nil
+	}
+	for _, m := range authModes {
+		if strings.EqualFold(s, m.name) {
+			grantType := m.grantType
+			return &grantType, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid auth mode %q: it must be %s", s, AuthModes)
+}
+
+func (g OAuthGrantType) String() string {
+	for _, m := range authModes {
+		if m.grantType == g {
+			return m.name
+		}
+	}
+	return fmt.Sprintf("OAuthGrantType(%d)", int(g))
+}
+
+// Credentials are credentials given on the command line. Empty fields fall
+// back to environment variables: AZURE_AUTH_MODE, AZURE_TENANT_ID,
+// AZURE_CLIENT_ID, AZURE_CLIENT_SECRET, AZURE_CERTIFICATE_PATH,
+// AZURE_CERTIFICATE_PASSWORD and MSI_ENDPOINT.
+type Credentials struct {
+	AuthMode        string
+	TenantID        string
+	ClientID        string
+	CertificatePath string
+	MSIEndpoint     string
+	// ReadClientSecret and ReadCertificatePassword read the secrets, e.g. from
+	// a file or stdin. They're only called once a service principal needs them,
+	// so that commands which don't authenticate never read them.
+	ReadClientSecret        func() (string, error)
+	ReadCertificatePassword func() (string, error)
+}
+
+// secret is a secret which is read when it's first needed.
+type secret struct {
+	once  sync.Once
+	read  func() (string, error)
+	value string
+	err   error
+}
+
+// newSecret returns a secret whose value is already known.
+func newSecret(value string) *secret {
+	return &secret{value: value}
+}
+
+// get returns the secret, reading it the first time.
+func (s *secret) get() (string, error) {
+	s.once.Do(func() {
+		if s.read != nil {
+			s.value, s.err = s.read()
+		}
+	})
+	return s.value, s.err
+}
+
+// Init reads the credentials from the environment and overrides them with the
+// non-empty fields of creds. It must be called before any authorizer is requested.
+func Init(creds Credentials) error {
+	if err := parseArgs(); err != nil {
+		return err
+	}
+
+	override := func(v *string, flag string) {
+		if flag != "" {
+			*v = flag
+		}
+	}
+	mode := os.Getenv("AZURE_AUTH_MODE")
+	override(&mode, creds.AuthMode)
+	override(&tenantID, creds.TenantID)
+	override(&clientID, creds.ClientID)
+	override(&certificatePath, creds.CertificatePath)
+	override(&msiEndpoint, creds.MSIEndpoint)
+	if creds.ReadClientSecret != nil {
+		clientSecret = &secret{read: creds.ReadClientSecret}
+	}
+	if creds.ReadCertificatePassword != nil {
+		certificatePassword = &secret{read: creds.ReadCertificatePassword}
+	}
+
+	grantType, err := ParseAuthMode(mode)
+	if err != nil {
+		return err
+	}
+	setAuthMode(grantType, hasLoginAccount())
+	return nil
+}
+
+// setAuthMode selects the grant type, or nil to pick one automatically, and
+// records whether `solstice login` cached credentials for the cloud.
+func setAuthMode(mode *OAuthGrantType, login bool) {
+	modeMu.Lock()
+	defer modeMu.Unlock()
+	authMode, loggedIn = mode, login
 }
 
 func parseArgs() error {
@@ -67,10 +194,12 @@ func parseArgs() error {
 
 	tenantID = os.Getenv("AZURE_TENANT_ID")
 	clientID = os.Getenv("AZURE_CLIENT_ID")
-	clientSecret = os.Getenv("AZURE_CLIENT_SECRET")
-
-	oauthConfig, err = adal.NewOAuthConfig(azure.PublicCloud.ActiveDirectoryEndpoint, tenantID)
-	return err
+	clientSecret = newSecret(os.Getenv("AZURE_CLIENT_SECRET"))
+	certificatePath = os.Getenv("AZURE_CERTIFICATE_PATH")
+	certificatePassword = newSecret(os.Getenv("AZURE_CERTIFICATE_PASSWORD"))
+	msiEndpoint = os.Getenv("MSI_ENDPOINT")
+	deviceFlow = os.Getenv("AZURE_AUTH_DEVICEFLOW") != ""
+	return nil
 }
 
 // ClientID gets the client ID
@@ -83,157 +212,163 @@ func TenantID() string {
 	return tenantID
 }
 
-// ClientSecret gets the client secret
+// ClientSecret gets the client secret. It's empty if the secret can't be read.
 func ClientSecret() string {
-	return clientSecret
+	s, _ := clientSecret.get()
+	return s
 }
 
-// AuthGrantType returns what kind of authentication is going to be used: device flow or service principal
+// AuthGrantType returns what kind of authentication is going to be used. An
+// auth mode given explicitly always wins. Otherwise a service principal is used
+// whenever a client ID is given, with a certificate if one is given and a
+// client secret if not; device flow when AZURE_AUTH_DEVICEFLOW is set; the
+// credentials cached by `solstice login` for the cloud if there are any; and the
+// az CLI token cache otherwise. Managed identities are only used when selected.
 func AuthGrantType() OAuthGrantType {
-	if helpers.DeviceFlow() {
+	modeMu.Lock()
+	mode, login := authMode, loggedIn
+	modeMu.Unlock()
+
+	if mode != nil {
+		return *mode
+	}
+	switch {
+	case clientID != "" && certificatePath != "":
+		return OAuthGrantTypeClientCertificate
+	case clientID != "":
+		return OAuthGrantTypeServicePrincipal
+	case deviceFlow:
 		return OAuthGrantTypeDeviceFlow
+	case login:
+		return OAuthGrantTypeLogin
 	}
-	return OAuthGrantTypeServicePrincipal
+	return OAuthGrantTypeCLI
 }
 
 // GetResourceManagementAuthorizer gets an OAuth token for managing resources using the specified grant type.
 func GetResourceManagementAuthorizer(grantType OAuthGrantType) (a autorest.Authorizer, err error) {
-	if armAuthorizer != nil {
-		fmt.Printf("Arm auth isn't nil: %v\n", armAuthorizer)
-		return armAuthorizer, nil
-	}
-
-	switch grantType {
-	case OAuthGrantTypeServicePrincipal:
-		fmt.Println("Authorizer from ~/.azure")
-		tokenPath, err := cli.AccessTokensPath()
-		if err != nil {
-			err = fmt.Errorf("There was an error while grabbing the access token path: %v", err)
-		}
-		tokens, err := cli.LoadTokens(tokenPath)
-		if err != nil {
-			err = fmt.Errorf("There was an error loading the tokens from %s: %v", tokenPath, err)
-		}
-		for _, token := range tokens {
-			adalToken, err := token.ToADALToken()
-			if err != nil {
-				continue
-			}
-			if adalToken.IsExpired() {
-				continue
-			}
-			a = autorest.NewBearerAuthorizer(&adalToken)
-			break
-		}
-		if a == nil {
-			err = fmt.Errorf("run `az login` to get started")
-		}
-	case OAuthGrantTypeDeviceFlow:
-		fmt.Println("Device flow")
-		config := auth.NewDeviceFlowConfig(samplesAppID, tenantID)
-		a, err = config.Authorizer()
-	default:
-		log.Fatalln("invalid token type specified")
-	}
+	return GetResourceManagementAuthorizerForTenant(grantType, "")
+}
 
-	if err == nil {
-		armAuthorizer = a
-	}
-	return
+// GetResourceManagementAuthorizerForTenant gets an OAuth token for managing the
+// resources of a subscription in the given tenant. The tenant selects which of
+// the tokens cached by `solstice login` or `az login` is used, and the tenant
+// service principals authenticate with; the default tenant is used if it's empty.
+func GetResourceManagementAuthorizerForTenant(grantType OAuthGrantType, tenant string) (autorest.Authorizer, error) {
+	return authorizers.authorizer(newAuthorizerKey(grantType, tenant, managementAudience()))
 }
 
-// GetBatchAuthorizer gets an authorizer for Azure batch using the specified grant type.
-func GetBatchAuthorizer(grantType OAuthGrantType) (a autorest.Authorizer, err error) {
-	if batchAuthorizer != nil {
-		return batchAuthorizer, nil
+// tenantConfig returns the OAuth configuration of a tenant for the configured
+// service principal, using the service principal's tenant if it's empty.
+func tenantConfig(tenant string) (*adal.OAuthConfig, error) {
+	if tenant == "" {
+		tenant = tenantID
 	}
-
-	a, err = getAuthorizer(grantType, azure.PublicCloud.BatchManagementEndpoint)
-	if err == nil {
-		batchAuthorizer = a
+	if tenant == "" {
+		return nil, errors.New("service principal authentication requires a tenant ID (--tenant-id or AZURE_TENANT_ID)")
+	}
+	if clientID == "" {
+		return nil, errors.New("service principal authentication requires a client ID (--client-id or AZURE_CLIENT_ID)")
 	}
+	return adal.NewOAuthConfig(environment.ActiveDirectoryEndpoint, tenant)
+}
 
-	return
+// GetBatchAuthorizer gets an authorizer for Azure batch using the specified grant type.
+func GetBatchAuthorizer(grantType OAuthGrantType) (autorest.Authorizer, error) {
+	return getAuthorizer(grantType, environment.BatchManagementEndpoint)
 }
 
 // GetGraphAuthorizer gets an authorizer for the graphrbac API using the specified grant type.
-func GetGraphAuthorizer(grantType OAuthGrantType) (a autorest.Authorizer, err error) {
-	if graphAuthorizer != nil {
-		return graphAuthorizer, nil
-	}
-
-	a, err = getAuthorizer(grantType, azure.PublicCloud.GraphEndpoint)
-	if err == nil {
-		graphAuthorizer = a
-	}
-
-	return
+func GetGraphAuthorizer(grantType OAuthGrantType) (autorest.Authorizer, error) {
+	return getAuthorizer(grantType, environment.GraphEndpoint)
 }
 
 // GetResourceManagementTokenHybrid retrieves auth token for hybrid environment
 func GetResourceManagementTokenHybrid(activeDirectoryEndpoint, tokenAudience string) (adal.OAuthTokenProvider, error) {
 	var token adal.OAuthTokenProvider
 	oauthConfig, err := adal.NewOAuthConfig(activeDirectoryEndpoint, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	value, err := clientSecret.get()
+	if err != nil {
+		return nil, err
+	}
 	token, err = adal.NewServicePrincipalToken(
 		*oauthConfig,
 		clientID,
-		clientSecret,
+		value,
 		tokenAudience)
 
 	return token, err
 }
 
-func getAuthorizer(grantType OAuthGrantType, endpoint string) (a autorest.Authorizer, err error) {
+// getAuthorizer gets an authorizer for a resource of the default tenant.
+func getAuthorizer(grantType OAuthGrantType, endpoint string) (autorest.Authorizer, error) {
+	return authorizers.authorizer(newAuthorizerKey(grantType, "", endpoint))
+}
+
+// getTokenProvider gets a token for a resource of a tenant using the specified
+// grant type. Managed identities ignore the tenant. Authorizers get their
+// tokens from the cache instead, which calls this for the tokens it lacks.
+func getTokenProvider(grantType OAuthGrantType, tenant, resource string) (adal.OAuthTokenProvider, error) {
 	switch grantType {
-	case OAuthGrantTypeServicePrincipal:
-		token, err := adal.NewServicePrincipalToken(*oauthConfig, clientID, clientSecret, endpoint)
-		if err != nil {
-			return a, err
+	case OAuthGrantTypeServicePrincipal, OAuthGrantTypeClientCertificate:
+		return servicePrincipalToken(grantType, tenant, resource)
+	case OAuthGrantTypeManagedIdentity:
+		return managedIdentityToken(resource)
+	case OAuthGrantTypeLogin:
+		return getLoginToken(tenant, resource)
+	case OAuthGrantTypeCLI:
+		if isManagementResource(resource) {
+			return getCLIToken(tenant)
 		}
-		a = autorest.NewBearerAuthorizer(token)
 	case OAuthGrantTypeDeviceFlow:
-		config := auth.NewDeviceFlowConfig(samplesAppID, tenantID)
-		config.Resource = endpoint
-		a, err = config.Authorizer()
-	default:
-		log.Fatalln("invalid token type specified")
+		if tenant == "" {
+			tenant = tenantID
+		}
+		return deviceFlowToken(samplesAppID, tenant, resource, os.Stderr)
 	}
-	return
+	return nil, fmt.Errorf("the grant type can't be used to get a token for %s", resource)
 }
 
-// GetKeyvaultAuthorizer gets an authorizer for the keyvault dataplane
-func GetKeyvaultAuthorizer(grantType OAuthGrantType) (a autorest.Authorizer, err error) {
-	if keyvaultAuthorizer != nil {
-		return keyvaultAuthorizer, nil
-	}
-
-	vaultEndpoint := "https://vault.azure.net"
-	config, err := adal.NewOAuthConfig(azure.PublicCloud.ActiveDirectoryEndpoint, tenantID)
-	updatedAuthorizeEndpoint, err := url.Parse("https://login.windows.net/" + tenantID + "/oauth2/token")
-	config.AuthorizeEndpoint = *updatedAuthorizeEndpoint
+// servicePrincipalToken gets a token for the configured service principal,
+// authenticating with its client secret or its certificate.
+func servicePrincipalToken(grantType OAuthGrantType, tenant, resource string) (*adal.ServicePrincipalToken, error) {
+	config, err := tenantConfig(tenant)
 	if err != nil {
-		return
+		return nil, err
 	}
-
-	switch grantType {
-	case OAuthGrantTypeServicePrincipal:
-		token, err := adal.NewServicePrincipalToken(*config, clientID, clientSecret, vaultEndpoint)
+	if grantType == OAuthGrantTypeClientCertificate {
+		if certificatePath == "" {
+			return nil, errors.New("certificate authentication requires a certificate (--certificate-file or AZURE_CERTIFICATE_PATH)")
+		}
+		password, err := certificatePassword.get()
 		if err != nil {
-			return a, err
+			return nil, err
 		}
-		a = autorest.NewBearerAuthorizer(token)
-	case OAuthGrantTypeDeviceFlow:
-		deviceConfig := auth.NewDeviceFlowConfig(samplesAppID, tenantID)
-		deviceConfig.Resource = vaultEndpoint
-		deviceConfig.AADEndpoint = updatedAuthorizeEndpoint.String()
-		a, err = deviceConfig.Authorizer()
-	default:
-		log.Fatalln("invalid token type specified")
+		return certificateToken(*config, clientID, certificatePath, password, resource)
+	}
+	value, err := clientSecret.get()
+	if err != nil {
+		return nil, err
 	}
+	if value == "" {
+		return nil, errors.New("service principal authentication requires a client secret (--client-secret-file or AZURE_CLIENT_SECRET)")
+	}
+	return adal.NewServicePrincipalToken(*config, clientID, value, resource)
+}
 
-	if err == nil {
-		keyvaultAuthorizer = a
+// certificateToken gets a token for a service principal authenticating with a certificate.
+func certificateToken(config adal.OAuthConfig, clientID, path, password, resource string) (*adal.ServicePrincipalToken, error) {
+	certificate, privateKey, err := loadCertificate(path, password)
+	if err != nil {
+		return nil, err
 	}
+	return adal.NewServicePrincipalTokenFromCertificate(config, clientID, certificate, privateKey, resource)
+}
 
-	return
+// GetKeyvaultAuthorizer gets an authorizer for the keyvault dataplane
+func GetKeyvaultAuthorizer(grantType OAuthGrantType) (autorest.Authorizer, error) {
+	return getAuthorizer(grantType, strings.TrimSuffix(environment.KeyVaultEndpoint, "/"))
 }
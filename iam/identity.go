@@ -0,0 +1,90 @@
+package iam
+
+import (
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure/cli"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// TokenInfo describes the token used to authenticate, without the token itself.
+type TokenInfo struct {
+	GrantType OAuthGrantType
+	// Source describes where the token came from, e.g. the az CLI's token cache.
+	Source string
+	// User is the UPN of a user; AppID is the client ID of a service principal
+	// or managed identity.
+	User      string
+	AppID     string
+	ObjectID  string
+	TenantID  string
+	Audience  string
+	ExpiresOn time.Time
+}
+
+// ResourceManagementTokenInfo gets the token GetResourceManagementAuthorizerForTenant
+// authenticates with, refreshing it if needed, and describes it.
+func ResourceManagementTokenInfo(grantType OAuthGrantType, tenant string) (*TokenInfo, error) {
+	e := authorizers.entry(newAuthorizerKey(grantType, tenant, managementAudience()))
+	p, err := e.tokenProvider(true)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := tokenClaims(p.OAuthToken())
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TokenInfo{
+		GrantType: grantType,
+		Source:    tokenSource(grantType),
+		User:      claimString(claims, "upn", "unique_name", "email"),
+		AppID:     claimString(claims, "appid", "azp"),
+		ObjectID:  claimString(claims, "oid"),
+		TenantID:  claimString(claims, "tid"),
+		Audience:  claimAudience(claims),
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		info.ExpiresOn = time.Unix(int64(exp), 0)
+	}
+	return info, nil
+}
+
+// tokenSource describes where the tokens of a grant type come from.
+func tokenSource(grantType OAuthGrantType) string {
+	switch grantType {
+	case OAuthGrantTypeLogin:
+		path, _ := loginCachePath()
+		return "solstice login cache " + path
+	case OAuthGrantTypeCLI:
+		path, _ := cli.AccessTokensPath()
+		return "az CLI cache " + path
+	case OAuthGrantTypeServicePrincipal:
+		return "service principal with a client secret"
+	case OAuthGrantTypeClientCertificate:
+		return "service principal with the certificate " + certificatePath
+	case OAuthGrantTypeManagedIdentity:
+		if msiEndpoint != "" {
+			return "managed identity from " + msiEndpoint
+		}
+		return "managed identity from the VM's metadata service"
+	case OAuthGrantTypeDeviceFlow:
+		return "device flow"
+	}
+	return grantType.String()
+}
+
+// claimAudience returns the audience of a token, which is either a string or a list.
+func claimAudience(claims jwt.MapClaims) string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud
+	case []interface{}:
+		if len(aud) > 0 {
+			if s, ok := aud[0].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,446 @@
+package iam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/go-autorest/autorest/azure/cli"
+	"github.com/ehotinger/solstice/pkg/config"
+)
+
+const (
+	// loginCacheFile is the name of the token cache of `solstice login` in the configuration directory.
+	loginCacheFile = "tokens.json"
+
+	accountTypeUser             = "user"
+	accountTypeServicePrincipal = "servicePrincipal"
+)
+
+// loginCacheLock serializes the updates of the token cache made by this process.
+var loginCacheLock sync.Mutex
+
+// loginCache holds the credentials cached by `solstice login`, one account per cloud.
+type loginCache struct {
+	Accounts []loginAccount `json:"accounts"`
+}
+
+// loginAccount is a user or service principal signed in to a cloud.
+type loginAccount struct {
+	// ActiveDirectoryEndpoint identifies the cloud of the account.
+	ActiveDirectoryEndpoint string `json:"activeDirectoryEndpoint"`
+	Type                    string `json:"type"`
+	// Name is the user's UPN or the service principal's client ID.
+	Name     string `json:"name"`
+	TenantID string `json:"tenantId"`
+	ClientID string `json:"clientId"`
+	// The credentials of service principals, which get new tokens with them.
+	ClientSecret        string `json:"clientSecret,omitempty"`
+	CertificatePath     string `json:"certificatePath,omitempty"`
+	CertificatePassword string `json:"certificatePassword,omitempty"`
+	// Tokens are the tokens of users, per tenant and resource.
+	Tokens        []loginToken       `json:"tokens,omitempty"`
+	Subscriptions []cli.Subscription `json:"subscriptions,omitempty"`
+}
+
+// loginToken is a user's token for a tenant.
+type loginToken struct {
+	TenantID string `json:"tenantId"`
+	adal.Token
+}
+
+func loginCachePath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, loginCacheFile), nil
+}
+
+// readLoginCache reads the token cache. A missing cache is empty.
+func readLoginCache(path string) (*loginCache, error) {
+	c := &loginCache{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// write saves the token cache, readable by the current user only.
+func (c *loginCache) write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// account returns the account signed in to the selected cloud, if any.
+func (c *loginCache) account() *loginAccount {
+	for i, a := range c.Accounts {
+		if sameEndpoint(a.ActiveDirectoryEndpoint, environment.ActiveDirectoryEndpoint) {
+			return &c.Accounts[i]
+		}
+	}
+	return nil
+}
+
+// token returns the user's token for a tenant and resource. Without one, it
+// returns an expired token holding a refresh token of the account, which gets
+// a token for any tenant and resource the user can access when refreshed.
+func (a *loginAccount) token(tenant, resource string) (adal.Token, bool) {
+	var refreshable *loginToken
+	for i, t := range a.Tokens {
+		if !strings.EqualFold(t.TenantID, tenant) {
+			if refreshable == nil && t.RefreshToken != "" {
+				refreshable = &a.Tokens[i]
+			}
+			continue
+		}
+		if sameEndpoint(t.Resource, resource) && (!t.IsExpired() || t.RefreshToken != "") {
+			return t.Token, true
+		}
+		if t.RefreshToken != "" {
+			refreshable = &a.Tokens[i]
+		}
+	}
+	if refreshable == nil {
+		return adal.Token{}, false
+	}
+	return adal.Token{
+		RefreshToken: refreshable.RefreshToken,
+		ExpiresOn:    "0",
+		Resource:     resource,
+		Type:         refreshable.Type,
+	}, true
+}
+
+func sameEndpoint(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "/"), strings.TrimSuffix(b, "/"))
+}
+
+// hasLoginAccount reports whether `solstice login` cached credentials for the selected cloud.
+func hasLoginAccount() bool {
+	path, err := loginCachePath()
+	if err != nil {
+		return false
+	}
+	c, err := readLoginCache(path)
+	return err == nil && c.account() != nil
+}
+
+// updateLoginCache applies update to the token cache and saves it.
+func updateLoginCache(update func(c *loginCache) error) error {
+	loginCacheLock.Lock()
+	defer loginCacheLock.Unlock()
+
+	path, err := loginCachePath()
+	if err != nil {
+		return err
+	}
+	c, err := readLoginCache(path)
+	if err != nil {
+		return err
+	}
+	if err = update(c); err != nil {
+		return err
+	}
+	if len(c.Accounts) == 0 {
+		if err = os.Remove(path); os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return c.write(path)
+}
+
+// saveLoginAccount replaces the account signed in to the selected cloud.
+func saveLoginAccount(account loginAccount) error {
+	account.ActiveDirectoryEndpoint = environment.ActiveDirectoryEndpoint
+	return updateLoginCache(func(c *loginCache) error {
+		if a := c.account(); a != nil {
+			*a = account
+		} else {
+			c.Accounts = append(c.Accounts, account)
+		}
+		return nil
+	})
+}
+
+// saveLoginToken replaces the user's token for the tenant and resource of a refreshed token.
+func saveLoginToken(tenant string, refreshed adal.Token) error {
+	return updateLoginCache(func(c *loginCache) error {
+		a := c.account()
+		if a == nil {
+			// Signed out in the meantime.
+			return nil
+		}
+		for i, t := range a.Tokens {
+			if strings.EqualFold(t.TenantID, tenant) && sameEndpoint(t.Resource, refreshed.Resource) {
+				if refreshed.RefreshToken == "" {
+					refreshed.RefreshToken = t.RefreshToken
+				}
+				a.Tokens[i].Token = refreshed
+				return nil
+			}
+		}
+		a.Tokens = append(a.Tokens, loginToken{TenantID: tenant, Token: refreshed})
+		return nil
+	})
+}
+
+// LoginWithDeviceCode signs a user in to the selected cloud with the device
+// code flow and caches their tokens. The instructions for the user are written
+// to w. Without a tenant, the user's home tenant is used. It returns the user's name.
+func LoginWithDeviceCode(tenant string, w io.Writer) (string, error) {
+	config := deviceFlowConfig(azCLIclientID, tenant, managementAudience())
+	token, err := deviceCodeToken(config, w)
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := tokenClaims(token.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	tokenTenant := claimString(claims, "tid")
+	if tokenTenant == "" {
+		return "", errors.New("the access token has no tenant")
+	}
+	account := loginAccount{
+		Type:     accountTypeUser,
+		Name:     claimString(claims, "upn", "unique_name", "email", "oid"),
+		TenantID: tokenTenant,
+		ClientID: config.ClientID,
+		Tokens:   []loginToken{{TenantID: tokenTenant, Token: *token}},
+	}
+	if err = saveLoginAccount(account); err != nil {
+		return "", err
+	}
+	useLogin()
+	return account.Name, nil
+}
+
+// deviceFlowConfig configures the device code flow for the selected cloud.
+// Without a tenant, the user's home tenant is used.
+func deviceFlowConfig(clientID, tenant, resource string) auth.DeviceFlowConfig {
+	if tenant == "" {
+		tenant = "common"
+	}
+	config := auth.NewDeviceFlowConfig(clientID, tenant)
+	config.AADEndpoint = environment.ActiveDirectoryEndpoint
+	config.Resource = resource
+	return config
+}
+
+// deviceCodeToken signs a user in with the device code flow, writing the
+// instructions for the user to w. Unlike config.Authorizer, it returns the
+// token, requested for config.Resource.
+func deviceCodeToken(config auth.DeviceFlowConfig, w io.Writer) (*adal.Token, error) {
+	oauthConfig, err := adal.NewOAuthConfig(config.AADEndpoint, config.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	sender := &autorest.Client{}
+	code, err := adal.InitiateDeviceAuth(sender, *oauthConfig, config.ClientID, config.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the device code flow: %v", err)
+	}
+	if code.Message != nil {
+		fmt.Fprintln(w, *code.Message)
+	}
+	token, err := adal.WaitForUserCompletion(sender, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish the device code flow: %v", err)
+	}
+	if token.Resource == "" {
+		token.Resource = config.Resource
+	}
+	return token, nil
+}
+
+// deviceFlowToken gets a token with the device code flow, which is refreshed
+// for as long as the process runs but isn't cached.
+func deviceFlowToken(clientID, tenant, resource string, w io.Writer) (*adal.ServicePrincipalToken, error) {
+	config := deviceFlowConfig(clientID, tenant, resource)
+	token, err := deviceCodeToken(config, w)
+	if err != nil {
+		return nil, err
+	}
+	oauthConfig, err := adal.NewOAuthConfig(config.AADEndpoint, config.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	return adal.NewServicePrincipalTokenFromManualToken(*oauthConfig, config.ClientID, config.Resource, *token)
+}
+
+// useLogin makes the rest of the process authenticate with the credentials
+// which were just cached.
+func useLogin() {
+	grantType := OAuthGrantTypeLogin
+	setAuthMode(&grantType, true)
+}
+
+// LoginServicePrincipal checks the credentials of the configured service
+// principal and caches them, so that later commands authenticate as the
+// service principal without them. It returns the service principal's client ID.
+func LoginServicePrincipal() (string, error) {
+	grantType := OAuthGrantTypeServicePrincipal
+	if certificatePath != "" {
+		grantType = OAuthGrantTypeClientCertificate
+	}
+	token, err := servicePrincipalToken(grantType, "", managementAudience())
+	if err != nil {
+		return "", err
+	}
+	if err = token.Refresh(); err != nil {
+		return "", fmt.Errorf("failed to authenticate as service principal %s: %v", clientID, err)
+	}
+
+	account := loginAccount{
+		Type:     accountTypeServicePrincipal,
+		Name:     clientID,
+		TenantID: tenantID,
+		ClientID: clientID,
+	}
+	if grantType == OAuthGrantTypeClientCertificate {
+		if account.CertificatePath, err = filepath.Abs(certificatePath); err != nil {
+			return "", err
+		}
+		// Both were read to get the token.
+		account.CertificatePassword, _ = certificatePassword.get()
+	} else {
+		account.ClientSecret, _ = clientSecret.get()
+	}
+	if err = saveLoginAccount(account); err != nil {
+		return "", err
+	}
+	useLogin()
+	return account.Name, nil
+}
+
+// Logout removes the credentials cached by `solstice login` for the selected
+// cloud. It returns the name of the account which was signed out, or an empty
+// string if none was signed in.
+func Logout() (string, error) {
+	var name string
+	err := updateLoginCache(func(c *loginCache) error {
+		for i, a := range c.Accounts {
+			if sameEndpoint(a.ActiveDirectoryEndpoint, environment.ActiveDirectoryEndpoint) {
+				name = a.Name
+				c.Accounts = append(c.Accounts[:i], c.Accounts[i+1:]...)
+				return nil
+			}
+		}
+		return nil
+	})
+	modeMu.Lock()
+	loggedIn = false
+	modeMu.Unlock()
+	return name, err
+}
+
+// SetLoginSubscriptions saves the subscriptions of the account signed in to
+// the selected cloud. The subscription marked as default is used when no
+// other one is selected, and subscriptions without a tenant get the account's.
+func SetLoginSubscriptions(subs []cli.Subscription) error {
+	return updateLoginCache(func(c *loginCache) error {
+		a := c.account()
+		if a == nil {
+			return errors.New("not logged in, run `solstice login`")
+		}
+		for i := range subs {
+			if subs[i].TenantID == "" {
+				subs[i].TenantID = a.TenantID
+			}
+		}
+		a.Subscriptions = subs
+		return nil
+	})
+}
+
+// LoginSubscriptions returns the subscriptions saved by `solstice login` for
+// the selected cloud, or an error if it wasn't used.
+func LoginSubscriptions() ([]cli.Subscription, error) {
+	path, err := loginCachePath()
+	if err != nil {
+		return nil, err
+	}
+	c, err := readLoginCache(path)
+	if err != nil {
+		return nil, err
+	}
+	a := c.account()
+	if a == nil {
+		return nil, fmt.Errorf("not logged in to %s, run `solstice login`", environment.Name)
+	}
+	return a.Subscriptions, nil
+}
+
+// getLoginToken uses the credentials cached by `solstice login` to get a token
+// for a resource of a tenant, or of the account's tenant if it's empty.
+// Refreshed user tokens are written back to the cache.
+func getLoginToken(tenant, resource string) (adal.OAuthTokenProvider, error) {
+	path, err := loginCachePath()
+	if err != nil {
+		return nil, err
+	}
+	c, err := readLoginCache(path)
+	if err != nil {
+		return nil, fmt.Errorf("There was an error loading the tokens from %s, run `solstice login`: %v", path, err)
+	}
+	account := c.account()
+	if account == nil {
+		return nil, fmt.Errorf("not logged in to %s, run `solstice login`", environment.Name)
+	}
+	if tenant == "" {
+		tenant = account.TenantID
+	}
+	config, err := adal.NewOAuthConfig(environment.ActiveDirectoryEndpoint, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.Type == accountTypeServicePrincipal {
+		var token *adal.ServicePrincipalToken
+		if account.CertificatePath != "" {
+			token, err = certificateToken(*config, account.ClientID, account.CertificatePath, account.CertificatePassword, resource)
+		} else {
+			token, err = adal.NewServicePrincipalToken(*config, account.ClientID, account.ClientSecret, resource)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return token, nil
+	}
+
+	token, ok := account.token(tenant, resource)
+	if !ok {
+		return nil, fmt.Errorf("the tokens of %s have expired, run `solstice login` again", account.Name)
+	}
+	spt, err := adal.NewServicePrincipalTokenFromManualToken(*config, account.ClientID, resource, token, func(refreshed adal.Token) error {
+		return saveLoginToken(tenant, refreshed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spt, nil
+}
@@ -0,0 +1,24 @@
+package iam
+
+import (
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+// managedIdentityToken returns a token for the managed identity of the VM,
+// requested from MSI_ENDPOINT (or --msi-endpoint) if set, and from the VM's
+// instance metadata service otherwise. A client ID selects a user assigned
+// identity instead of the system assigned one.
+func managedIdentityToken(resource string) (*adal.ServicePrincipalToken, error) {
+	endpoint := msiEndpoint
+	if endpoint == "" {
+		var err error
+		if endpoint, err = adal.GetMSIVMEndpoint(); err != nil {
+			return nil, err
+		}
+	}
+
+	if clientID != "" {
+		return adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(endpoint, resource, clientID)
+	}
+	return adal.NewServicePrincipalTokenFromMSI(endpoint, resource)
+}
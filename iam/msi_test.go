@@ -0,0 +1,92 @@
+package iam
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/ehotinger/solstice/iam/msitest"
+)
+
+// useManagedIdentity authenticates as the managed identity served by srv, with
+// an empty authorizer cache, until the returned function is called.
+func useManagedIdentity(srv *msitest.Server, userAssignedID string) func() {
+	oldEndpoint, oldClientID, oldAuthorizers := msiEndpoint, clientID, authorizers
+	msiEndpoint, clientID, authorizers = srv.URL, userAssignedID, newAuthorizerCache()
+	return func() {
+		msiEndpoint, clientID, authorizers = oldEndpoint, oldClientID, oldAuthorizers
+	}
+}
+
+// bearerToken returns the bearer token an authorizer adds to a request.
+func bearerToken(t *testing.T, a autorest.Authorizer) string {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "https://management.azure.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = autorest.Prepare(r, a.WithAuthorization())
+	if err != nil {
+		t.Fatalf("failed to authorize the request: %v", err)
+	}
+	return r.Header.Get("Authorization")
+}
+
+func TestManagedIdentity(t *testing.T) {
+	tests := []struct {
+		name           string
+		userAssignedID string
+	}{
+		{"system assigned", ""},
+		{"user assigned", "00000000-0000-0000-0000-000000000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := msitest.NewServer("msi-token")
+			defer srv.Close()
+			defer useManagedIdentity(srv, tt.userAssignedID)()
+
+			a, err := GetResourceManagementAuthorizerForTenant(OAuthGrantTypeManagedIdentity, "ignored-tenant")
+			if err != nil {
+				t.Fatalf("failed to get an authorizer: %v", err)
+			}
+			if got := bearerToken(t, a); got != "Bearer msi-token" {
+				t.Errorf("Authorization = %q, want the managed identity's token", got)
+			}
+
+			requests := srv.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("got %d token requests, want 1", len(requests))
+			}
+			if requests[0].Resource != managementAudience() {
+				t.Errorf("the token was requested for %s, want %s", requests[0].Resource, managementAudience())
+			}
+			if requests[0].ClientID != tt.userAssignedID {
+				t.Errorf("the token was requested for client ID %q, want %q", requests[0].ClientID, tt.userAssignedID)
+			}
+		})
+	}
+}
+
+func TestManagedIdentityIsOnlySelectedExplicitly(t *testing.T) {
+	oldMode, oldLoggedIn, oldClientID, oldEndpoint := authMode, loggedIn, clientID, msiEndpoint
+	defer func() {
+		setAuthMode(oldMode, oldLoggedIn)
+		clientID, msiEndpoint = oldClientID, oldEndpoint
+	}()
+
+	setAuthMode(nil, false)
+	clientID, msiEndpoint = "", "http://127.0.0.1:1"
+	if g := AuthGrantType(); g == OAuthGrantTypeManagedIdentity {
+		t.Error("a managed identity was used without being selected")
+	}
+
+	mode, err := ParseAuthMode("msi")
+	if err != nil {
+		t.Fatalf("ParseAuthMode(msi) errored: %v", err)
+	}
+	setAuthMode(mode, false)
+	if g := AuthGrantType(); g != OAuthGrantTypeManagedIdentity {
+		t.Errorf("AuthGrantType() = %v with --auth-mode msi", g)
+	}
+}
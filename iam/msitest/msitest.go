@@ -0,0 +1,82 @@
+// Package msitest provides a local stand-in for the managed identity (MSI)
+// endpoint of Azure VMs, so that managed identity authentication can be tested
+// without a VM. Point MSI_ENDPOINT or --msi-endpoint at the server's URL and
+// select --auth-mode msi.
+package msitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request is a token request received by the stand-in.
+type Request struct {
+	Resource string
+	// ClientID is the client ID of the requested user assigned identity, if any.
+	ClientID string
+}
+
+// Server is a stand-in MSI endpoint handing out a fixed access token.
+type Server struct {
+	*httptest.Server
+
+	token     string
+	expiresIn time.Duration
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// NewServer starts a stand-in MSI endpoint handing out token, valid for an hour.
+// Close it once it's no longer needed.
+func NewServer(token string) *Server {
+	return NewServerExpiringIn(token, time.Hour)
+}
+
+// NewServerExpiringIn starts a stand-in MSI endpoint handing out token, valid
+// for expiresIn, e.g. to test that tokens about to expire are refreshed.
+func NewServerExpiringIn(token string, expiresIn time.Duration) *Server {
+	s := &Server{token: token, expiresIn: expiresIn}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveToken))
+	return s
+}
+
+// Requests returns the token requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	// Like the real endpoint, require the header which guards against SSRF.
+	if r.Header.Get("Metadata") != "true" {
+		http.Error(w, `{"error":"invalid_request","error_description":"Required metadata header not specified"}`, http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	resource := query.Get("resource")
+	if resource == "" {
+		http.Error(w, `{"error":"invalid_request","error_description":"Required query variable 'resource' is missing"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Resource: resource, ClientID: query.Get("client_id")})
+	s.mu.Unlock()
+
+	now := time.Now()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": s.token,
+		"expires_in":   strconv.Itoa(int(s.expiresIn / time.Second)),
+		"expires_on":   strconv.FormatInt(now.Add(s.expiresIn).Unix(), 10),
+		"not_before":   strconv.FormatInt(now.Unix(), 10),
+		"resource":     resource,
+		"token_type":   "Bearer",
+	})
+}
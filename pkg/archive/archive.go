@@ -0,0 +1,129 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ehotinger/solstice/pkg/fileutils"
+)
+
+// TarGz writes the contents of the directory at root to w as a gzip compressed tarball.
+// Paths inside the archive are relative to root and always use forward slashes.
+// Files matching the .dockerignore style excludes patterns are left out.
+func TarGz(root string, excludes []string, w io.Writer) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+
+	pm, err := fileutils.NewPatternMatcher(excludes)
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		skip, err := pm.Matches(rel)
+		if err != nil {
+			return err
+		}
+		if skip {
+			return skipPath(pm, rel, fi)
+		}
+
+		return addFile(tw, path, filepath.ToSlash(rel), fi)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// skipPath decides how to skip an excluded path. Excluded directories are only
+// walked when an exclusion pattern (e.g. !dir/file) may re-include something inside them.
+func skipPath(pm *fileutils.PatternMatcher, rel string, fi os.FileInfo) error {
+	if !fi.IsDir() {
+		return nil
+	}
+	if !pm.Exclusions() {
+		return filepath.SkipDir
+	}
+
+	dirSlash := rel + string(filepath.Separator)
+	for _, pat := range pm.Patterns() {
+		if !pat.Exclusion() {
+			continue
+		}
+		if strings.HasPrefix(pat.String()+string(filepath.Separator), dirSlash) {
+			return nil
+		}
+	}
+	return filepath.SkipDir
+}
+
+// addFile writes a single entry to the tarball. Only directories, symlinks and
+// regular files are archived; other file types (sockets, devices, ...) are skipped.
+func addFile(tw *tar.Writer, path string, name string, fi os.FileInfo) error {
+	mode := fi.Mode()
+	if !mode.IsDir() && !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+		return nil
+	}
+
+	var link string
+	if mode&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+
+	hdr, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if mode.IsDir() {
+		hdr.Name += "/"
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !mode.IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTarGz(t *testing.T) {
+	root, err := ioutil.TempDir("", "solstice-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"Dockerfile":              "FROM scratch",
+		"main.go":                 "package main",
+		"docs/guide.md":           "guide",
+		"docs/keep.md":            "keep",
+		"src/app/build.tmp":       "tmp",
+		"src/app/app.go":          "package app",
+		"node_modules/a/index.js": "js",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	excludes := []string{"docs", "!docs/keep.md", "**/*.tmp", "node_modules"}
+	if err := TarGz(root, excludes, &buf); err != nil {
+		t.Fatalf("TarGz errored: %v", err)
+	}
+
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Typeflag == tar.TypeReg {
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != files[hdr.Name] {
+				t.Errorf("%s holds %q, want %q", hdr.Name, content, files[hdr.Name])
+			}
+		}
+	}
+	sort.Strings(names)
+
+	want := []string{"Dockerfile", "docs/keep.md", "main.go", "src/", "src/app/", "src/app/app.go"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("the archive holds %q, want %q", names, want)
+	}
+}
+
+func TestTarGzNotADirectory(t *testing.T) {
+	f, err := ioutil.TempFile("", "solstice-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if err := TarGz(f.Name(), nil, ioutil.Discard); err == nil {
+		t.Error("TarGz of a file didn't error")
+	}
+}
@@ -1,21 +1,52 @@
 package blob
 
 import (
+	"context"
 	"net/url"
+	"os"
 	"time"
 
+	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
 )
 
 // GetAppendBlobURL returns an AppendBlobURL for the specified logFileURL.
 func GetAppendBlobURL(logFileURL string) azblob.AppendBlobURL {
+	u, _ := url.Parse(logFileURL)
+
+	appendBlobURL := azblob.NewAppendBlobURL(*u, newPipeline(time.Second*10))
+	return appendBlobURL
+}
+
+// GetBlockBlobURL returns a BlockBlobURL for the specified SAS URL.
+func GetBlockBlobURL(sasURL string) (azblob.BlockBlobURL, error) {
+	u, err := url.Parse(sasURL)
+	if err != nil {
+		return azblob.BlockBlobURL{}, err
+	}
+	return azblob.NewBlockBlobURL(*u, newPipeline(time.Minute*5)), nil
+}
+
+// UploadFile uploads the contents of f to the block blob located at sasURL.
+func UploadFile(ctx context.Context, sasURL string, f *os.File) error {
+	blockBlobURL, err := GetBlockBlobURL(sasURL)
+	if err != nil {
+		return err
+	}
+	_, err = azblob.UploadFileToBlockBlob(ctx, f, blockBlobURL, azblob.UploadToBlockBlobOptions{})
+	return err
+}
+
+// newPipeline creates a retrying pipeline. tryTimeout bounds every individual HTTP request,
+// so uploads need a much larger value than small reads.
+func newPipeline(tryTimeout time.Duration) pipeline.Pipeline {
 	po := azblob.PipelineOptions{}
 	po.Retry = azblob.RetryOptions{
 		Policy:   azblob.RetryPolicyExponential,
 		MaxTries: 3,
 
 		// Maximum time allowed for any HTTP request
-		TryTimeout: time.Second * 10,
+		TryTimeout: tryTimeout,
 
 		// Retry delay between requests
 		RetryDelay: time.Second * 3,
@@ -24,9 +55,5 @@ func GetAppendBlobURL(logFileURL string) azblob.AppendBlobURL {
 		MaxRetryDelay: time.Second * 3,
 	}
 
-	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), po)
-	u, _ := url.Parse(logFileURL)
-
-	appendBlobURL := azblob.NewAppendBlobURL(*u, p)
-	return appendBlobURL
+	return azblob.NewPipeline(azblob.NewAnonymousCredential(), po)
 }
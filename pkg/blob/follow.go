@@ -0,0 +1,57 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+)
+
+// FollowAppendBlob copies the contents of an append blob to out as it grows.
+// The blob is polled every interval, reading only the bytes written since the
+// previous poll. done is consulted before every poll; once it reports true the
+// remaining bytes are copied and FollowAppendBlob returns.
+func FollowAppendBlob(ctx context.Context, blobURL azblob.AppendBlobURL, out io.Writer, interval time.Duration, done func() (bool, error)) error {
+	var offset int64
+	for {
+		finished, err := done()
+		if err != nil {
+			return err
+		}
+
+		n, err := copyFrom(ctx, blobURL, offset, out)
+		offset += n
+		if err != nil {
+			return err
+		}
+		if finished {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// copyFrom copies everything after offset to out and returns the number of bytes copied.
+// A blob which doesn't exist yet or hasn't grown past offset isn't an error.
+func copyFrom(ctx context.Context, blobURL azblob.AppendBlobURL, offset int64, out io.Writer) (int64, error) {
+	get, err := blobURL.GetBlob(ctx, azblob.BlobRange{Offset: offset}, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		if serr, ok := err.(azblob.StorageError); ok {
+			switch serr.ServiceCode() {
+			case azblob.ServiceCodeInvalidRange, azblob.ServiceCodeBlobNotFound:
+				return 0, nil
+			}
+		}
+		return 0, err
+	}
+
+	body := get.Body()
+	defer body.Close()
+	return io.Copy(out, body)
+}
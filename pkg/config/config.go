@@ -0,0 +1,73 @@
+// Package config reads solstice's configuration file, ~/.solstice/config.json.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Config holds the defaults used when neither flags nor environment variables
+// select a value.
+type Config struct {
+	// Cloud is the name of an Azure cloud, the path of an environment JSON file
+	// (relative to the configuration directory) or a Resource Manager URL.
+	Cloud string `json:"cloud,omitempty"`
+	// Subscription is the ID or name of the subscription to use.
+	Subscription string `json:"subscription,omitempty"`
+}
+
+// Dir returns the directory holding solstice's configuration and state.
+func Dir() (string, error) {
+	dir, err := homedir.Expand("~/.solstice")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the home directory: %v", err)
+	}
+	return dir, nil
+}
+
+// Path returns the path of the configuration file.
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// Load reads the configuration file. A missing file is an empty configuration.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Resolve makes a path found in the configuration file absolute, relative to
+// the configuration directory.
+func Resolve(path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil || path == "" || filepath.IsAbs(path) {
+		return path, err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, path), nil
+}
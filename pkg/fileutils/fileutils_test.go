@@ -0,0 +1,89 @@
+package fileutils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		file     string
+		want     bool
+	}{
+		{"exact", []string{"foo"}, "foo", true},
+		{"no match", []string{"foo"}, "bar", false},
+		{"star", []string{"*.go"}, "main.go", true},
+		{"star stops at separator", []string{"*.go"}, "cmd/main.go", false},
+		{"question mark", []string{"fo?"}, "foo", true},
+		{"question mark stops at separator", []string{"fo?"}, "fo/", false},
+		{"parent directory", []string{"vendor"}, "vendor/github.com/x/y.go", true},
+		{"nested pattern parent directory", []string{"a/b"}, "a/b/c/d", true},
+		{"double star any depth", []string{"**/*.go"}, "a/b/c.go", true},
+		{"double star zero depth", []string{"**/*.go"}, "c.go", true},
+		{"double star in the middle", []string{"a/**/c"}, "a/b/x/c", true},
+		{"double star in the middle zero depth", []string{"a/**/c"}, "a/c", true},
+		{"trailing double star", []string{"a/**"}, "a/b/c", true},
+		{"trailing double star other dir", []string{"a/**"}, "b/c", false},
+		{"dot is literal", []string{"a.b"}, "axb", false},
+		{"escaped star", []string{`a\*`}, "a*", true},
+		{"escaped star is literal", []string{`a\*`}, "ab", false},
+		{"exclusion re-includes", []string{"*.md", "!README.md"}, "README.md", false},
+		{"exclusion leaves others", []string{"*.md", "!README.md"}, "CHANGELOG.md", true},
+		{"last pattern wins", []string{"!README.md", "*.md"}, "README.md", true},
+		{"exclusion in excluded directory", []string{"docs", "!docs/keep.md"}, "docs/keep.md", false},
+		{"exclusion in excluded directory others", []string{"docs", "!docs/keep.md"}, "docs/drop.md", true},
+		{"exclusion with double star", []string{"**/*.log", "!**/keep.log"}, "a/b/keep.log", false},
+		{"pattern is cleaned", []string{"./a/../b/"}, "b", true},
+		{"pattern whitespace is trimmed", []string{"  foo  "}, "foo", true},
+		{"file is cleaned", []string{"a/b"}, "./a//b", true},
+		{"empty patterns are ignored", []string{"", "  "}, "foo", false},
+		{"root is never matched", []string{"*"}, ".", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Matches(filepath.FromSlash(tt.file), tt.patterns)
+			if err != nil {
+				t.Fatalf("Matches(%q, %q) errored: %v", tt.file, tt.patterns, err)
+			}
+			if got != tt.want {
+				t.Errorf("Matches(%q, %q) = %v, want %v", tt.file, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPatternMatcher(t *testing.T) {
+	pm, err := NewPatternMatcher([]string{"*.md", "!README.md", "./docs/"})
+	if err != nil {
+		t.Fatalf("NewPatternMatcher errored: %v", err)
+	}
+	if !pm.Exclusions() {
+		t.Error("Exclusions() = false, want true")
+	}
+	var got []string
+	for _, p := range pm.Patterns() {
+		got = append(got, p.String())
+	}
+	want := []string{"*.md", "README.md", "docs"}
+	if len(got) != len(want) {
+		t.Fatalf("Patterns() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Patterns()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if pm.Patterns()[0].Exclusion() || !pm.Patterns()[1].Exclusion() {
+		t.Error("only the second pattern should be an exclusion")
+	}
+}
+
+func TestNewPatternMatcherErrors(t *testing.T) {
+	for _, patterns := range [][]string{{"!"}, {"["}, {"a/[b"}} {
+		if _, err := NewPatternMatcher(patterns); err == nil {
+			t.Errorf("NewPatternMatcher(%q) didn't error", patterns)
+		}
+	}
+}
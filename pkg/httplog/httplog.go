@@ -0,0 +1,200 @@
+// Package httplog traces HTTP requests and responses for debugging, redacting
+// credentials and secret values before they are written.
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// Mask replaces redacted values.
+const Mask = "*****"
+
+var (
+	// redactedHeaders are never written.
+	redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Ms-Encryption-Key"}
+
+	// redactedFields are JSON fields which always hold credentials.
+	redactedFields = map[string]bool{
+		"token":         true,
+		"refreshtoken":  true,
+		"accesstoken":   true,
+		"clientsecret":  true,
+		"access_token":  true,
+		"refresh_token": true,
+		"id_token":      true,
+		"client_secret": true,
+		"password":      true,
+		"assertion":     true,
+	}
+
+	// sasSignatureRegexp matches the signature of SAS URLs, e.g. log links and upload URLs.
+	sasSignatureRegexp = regexp.MustCompile(`([?&]sig=)[^&"\s]+`)
+)
+
+// Sender is an autorest.Sender which writes every request and response it sends to a trace.
+type Sender struct {
+	sender autorest.Sender
+	out    io.Writer
+}
+
+// NewSender returns a Sender tracing to out. Requests are sent with sender,
+// or with a default http.Client if it's nil.
+func NewSender(sender autorest.Sender, out io.Writer) *Sender {
+	if sender == nil {
+		sender = &http.Client{}
+	}
+	return &Sender{sender: sender, out: out}
+}
+
+// Do implements autorest.Sender.
+func (s *Sender) Do(r *http.Request) (*http.Response, error) {
+	if err := s.writeRequest(r); err != nil {
+		return nil, err
+	}
+	resp, err := s.sender.Do(r)
+	if err != nil {
+		fmt.Fprintf(s.out, "<<< %s %s failed: %v\n\n", r.Method, RedactURL(r.URL.String()), err)
+		return resp, err
+	}
+	if err = s.writeResponse(resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (s *Sender) writeRequest(r *http.Request) error {
+	body, err := readBody(&r.Body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, ">>> %s %s\n", r.Method, RedactURL(r.URL.String()))
+	writeHeaders(s.out, r.Header)
+	writeBody(s.out, body)
+	return nil
+}
+
+func (s *Sender) writeResponse(resp *http.Response) error {
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, "<<< %s %s\n", resp.Status, RedactURL(resp.Request.URL.String()))
+	writeHeaders(s.out, resp.Header)
+	writeBody(s.out, body)
+	return nil
+}
+
+// readBody reads a request or response body and replaces it with an unread copy.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+func writeHeaders(out io.Writer, header http.Header) {
+	h := make(http.Header, len(header))
+	for k, v := range header {
+		h[k] = v
+	}
+	for _, k := range redactedHeaders {
+		if h.Get(k) != "" {
+			h.Set(k, Mask)
+		}
+	}
+	for _, k := range []string{"Location", "Azure-Asyncoperation"} {
+		if v := h.Get(k); v != "" {
+			h.Set(k, RedactURL(v))
+		}
+	}
+	h.Write(out)
+}
+
+func writeBody(out io.Writer, body []byte) {
+	if len(body) > 0 {
+		fmt.Fprintf(out, "\n%s\n", Redact(body))
+	}
+	fmt.Fprintln(out)
+}
+
+// RedactURL masks the signature of a SAS URL.
+func RedactURL(u string) string {
+	return sasSignatureRegexp.ReplaceAllString(u, "${1}"+Mask)
+}
+
+// Redact masks credentials and secret values in a request or response body.
+//
+// JSON bodies are redacted field by field: credential fields such as tokens are
+// always masked, as is the value of any object marked "isSecret": true, like a
+// secret build argument. Other bodies, e.g. form encoded token requests, are
+// masked entirely if they contain a credential field.
+func Redact(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		if containsCredentialField(string(body)) {
+			return Mask
+		}
+		return RedactURL(string(body))
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactValue(v)); err != nil {
+		return Mask
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		secret, _ := t["isSecret"].(bool)
+		for k, val := range t {
+			switch {
+			case redactedFields[strings.ToLower(k)]:
+				t[k] = Mask
+			case secret && k == "value":
+				t[k] = Mask
+			default:
+				t[k] = redactValue(val)
+			}
+		}
+		return t
+	case []interface{}:
+		for i, val := range t {
+			t[i] = redactValue(val)
+		}
+		return t
+	case string:
+		return RedactURL(t)
+	}
+	return v
+}
+
+func containsCredentialField(body string) bool {
+	body = strings.ToLower(body)
+	for field := range redactedFields {
+		if strings.Contains(body, field) {
+			return true
+		}
+	}
+	return false
+}
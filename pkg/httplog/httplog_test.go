@@ -0,0 +1,183 @@
+package httplog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{
+			"https://acct.blob.core.windows.net/logs/1.log?sv=2017&sr=b&sig=abc%2Fdef%3D&se=2018",
+			"https://acct.blob.core.windows.net/logs/1.log?sv=2017&sr=b&sig=" + Mask + "&se=2018",
+		},
+		{
+			"https://acct.blob.core.windows.net/source/ctx.tar.gz?sig=abcdef",
+			"https://acct.blob.core.windows.net/source/ctx.tar.gz?sig=" + Mask,
+		},
+		{
+			"https://management.azure.com/subscriptions/x?api-version=2018-02-01-preview",
+			"https://management.azure.com/subscriptions/x?api-version=2018-02-01-preview",
+		},
+		{
+			// Only the sig parameter is a signature.
+			"https://example.com/?design=1",
+			"https://example.com/?design=1",
+		},
+	}
+	for _, tt := range tests {
+		if got := RedactURL(tt.in); got != tt.want {
+			t.Errorf("RedactURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		secrets []string
+	}{
+		{
+			name: "secret build argument",
+			body: `{"type":"DockerBuildArgument","name":"PASSWORD","value":"hunter2","isSecret":true}`,
+			want: `{"isSecret":true,"name":"PASSWORD","type":"DockerBuildArgument","value":"` + Mask + `"}`,
+		},
+		{
+			name: "plain build argument",
+			body: `{"name":"VERSION","value":"1.0","isSecret":false}`,
+			want: `{"isSecret":false,"name":"VERSION","value":"1.0"}`,
+		},
+		{
+			name:    "nested build arguments",
+			body:    `{"properties":{"buildArguments":[{"name":"A","value":"visible","isSecret":false},{"name":"B","value":"hunter2","isSecret":true}]}}`,
+			secrets: []string{"hunter2"},
+		},
+		{
+			name:    "source control token",
+			body:    `{"properties":{"sourceRepository":{"sourceControlAuthProperties":{"tokenType":"PAT","token":"ghp_secret","refreshToken":"rt_secret"}}}}`,
+			secrets: []string{"ghp_secret", "rt_secret"},
+		},
+		{
+			name:    "OAuth token response",
+			body:    `{"token_type":"Bearer","access_token":"eyJ.access","refresh_token":"rt","id_token":"eyJ.id"}`,
+			secrets: []string{"eyJ.access", `"rt"`, "eyJ.id"},
+		},
+		{
+			name:    "camel case token fields",
+			body:    `{"accessToken":"eyJ.access","clientSecret":"cs_secret"}`,
+			secrets: []string{"eyJ.access", "cs_secret"},
+		},
+		{
+			name:    "SAS URL in JSON",
+			body:    `{"logLink":"https://acct.blob.core.windows.net/logs/1.log?sv=2017&sig=sassig"}`,
+			secrets: []string{"sassig"},
+		},
+		{
+			name: "form encoded token request",
+			body: "grant_type=client_credentials&client_id=app&client_secret=s3cret&resource=https%3A%2F%2Fmanagement.azure.com%2F",
+			want: Mask,
+		},
+		{
+			name: "form encoded refresh request",
+			body: "grant_type=refresh_token&refresh_token=rt_secret",
+			want: Mask,
+		},
+		{
+			name: "plain text",
+			body: "not json",
+			want: "not json",
+		},
+		{
+			name:    "plain text SAS URL",
+			body:    "upload to https://acct.blob.core.windows.net/c/b?sig=sassig",
+			secrets: []string{"sassig"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Redact([]byte(tt.body))
+			if tt.want != "" && got != tt.want {
+				t.Errorf("Redact(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+			for _, s := range tt.secrets {
+				if strings.Contains(got, s) {
+					t.Errorf("Redact(%s) = %s, which contains %s", tt.body, got, s)
+				}
+			}
+		})
+	}
+}
+
+type fakeSender struct {
+	resp *http.Response
+}
+
+func (s *fakeSender) Do(r *http.Request) (*http.Response, error) {
+	s.resp.Request = r
+	return s.resp, nil
+}
+
+func TestSender(t *testing.T) {
+	respBody := `{"properties":{"buildArguments":[{"name":"B","value":"resp_secret","isSecret":true}]}}`
+	sender := &fakeSender{resp: &http.Response{
+		Status:     "202 Accepted",
+		StatusCode: http.StatusAccepted,
+		Header: http.Header{
+			"Location":             {"https://acct.blob.core.windows.net/c/b?sv=1&sig=location_sig"},
+			"Azure-Asyncoperation": {"https://management.azure.com/op?sig=async_sig"},
+			"Set-Cookie":           {"session=cookie_secret"},
+		},
+		Body: ioutil.NopCloser(strings.NewReader(respBody)),
+	}}
+
+	reqBody := `{"buildArguments":[{"name":"A","value":"req_secret","isSecret":true}]}`
+	req, err := http.NewRequest(http.MethodPut, "https://acct.blob.core.windows.net/c/b?sig=url_sig", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer eyJ.bearer")
+	req.Header.Set("Cookie", "session=cookie_secret")
+	req.Header.Set("X-Ms-Encryption-Key", "encryption_key")
+
+	var trace bytes.Buffer
+	resp, err := NewSender(sender, &trace).Do(req)
+	if err != nil {
+		t.Fatalf("Do errored: %v", err)
+	}
+
+	out := trace.String()
+	for _, s := range []string{"eyJ.bearer", "cookie_secret", "encryption_key", "url_sig", "location_sig", "async_sig", "req_secret", "resp_secret"} {
+		if strings.Contains(out, s) {
+			t.Errorf("the trace contains %s:\n%s", s, out)
+		}
+	}
+	for _, s := range []string{">>> PUT", "<<< 202 Accepted", "Authorization: " + Mask, `"name":"A"`} {
+		if !strings.Contains(out, s) {
+			t.Errorf("the trace doesn't contain %s:\n%s", s, out)
+		}
+	}
+
+	// The bodies are still readable, and unredacted, by the caller and the server.
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil || string(b) != respBody {
+		t.Errorf("response body = %s, %v, want %s", b, err, respBody)
+	}
+	b, err = ioutil.ReadAll(req.Body)
+	if err != nil || string(b) != reqBody {
+		t.Errorf("request body = %s, %v, want %s", b, err, reqBody)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer eyJ.bearer" {
+		t.Errorf("the request's Authorization header was changed to %s", got)
+	}
+	if got := resp.Header.Get("Location"); !strings.Contains(got, "location_sig") {
+		t.Errorf("the response's Location header was changed to %s", got)
+	}
+}
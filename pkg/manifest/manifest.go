@@ -0,0 +1,216 @@
+// Package manifest defines the YAML/JSON format describing the build tasks and
+// build steps of a registry, as used by 'solstice apply'.
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// APIVersion is the version of the manifest format.
+const APIVersion = "solstice/v1"
+
+// Manifest describes build tasks and their steps.
+//
+// Optional fields which are left out aren't managed: they get the service's
+// defaults when a task or step is created and are left alone when it's updated.
+type Manifest struct {
+	APIVersion string      `json:"apiVersion"`
+	BuildTasks []BuildTask `json:"buildTasks"`
+}
+
+// BuildTask describes a build task.
+type BuildTask struct {
+	Name     string            `json:"name"`
+	Alias    string            `json:"alias,omitempty"`
+	Status   string            `json:"status,omitempty"`
+	Location string            `json:"location"`
+	Platform *Platform         `json:"platform,omitempty"`
+	Timeout  *int32            `json:"timeout,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Source   Source            `json:"source"`
+	Steps    []Step            `json:"steps,omitempty"`
+}
+
+// Platform describes the platform builds run on.
+type Platform struct {
+	OS  string `json:"os"`
+	CPU *int32 `json:"cpu,omitempty"`
+}
+
+// Source describes the source repository of a build task. The token is only
+// needed to create the task; it's never written to or read from the manifest
+// itself, but from a file or environment variable.
+type Source struct {
+	Type          string `json:"type"`
+	RepositoryURL string `json:"repositoryUrl"`
+	CommitTrigger *bool  `json:"commitTrigger,omitempty"`
+	TokenType     string `json:"tokenType,omitempty"`
+	TokenFile     string `json:"tokenFile,omitempty"`
+	TokenEnv      string `json:"tokenEnv,omitempty"`
+}
+
+// Step describes a Docker build step. Base images are given in the
+// [TYPE=]REPOSITORY[:TAG] form of 'step create --base-image'.
+type Step struct {
+	Name             string     `json:"name"`
+	Branch           string     `json:"branch,omitempty"`
+	Image            string     `json:"image,omitempty"`
+	Push             *bool      `json:"push,omitempty"`
+	Dockerfile       string     `json:"dockerfile,omitempty"`
+	Context          string     `json:"context,omitempty"`
+	BaseImageTrigger string     `json:"baseImageTrigger,omitempty"`
+	BaseImages       []string   `json:"baseImages,omitempty"`
+	BuildArgs        []BuildArg `json:"buildArgs,omitempty"`
+}
+
+// BuildArg describes a build argument. Secret values are never part of the
+// manifest, they're read from a file or an environment variable.
+type BuildArg struct {
+	Name      string `json:"name"`
+	Value     string `json:"value,omitempty"`
+	Secret    bool   `json:"secret,omitempty"`
+	ValueFile string `json:"valueFile,omitempty"`
+	ValueEnv  string `json:"valueEnv,omitempty"`
+}
+
+// nameRegexp matches the names the service accepts for build tasks and steps.
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]{5,50}$`)
+
+// Read parses and validates a manifest in YAML or JSON form.
+func Read(r io.Reader) (*Manifest, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse parses and validates a manifest in YAML or JSON form.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	return &m, nil
+}
+
+// Marshal renders a manifest as YAML.
+func Marshal(m *Manifest) ([]byte, error) {
+	return yaml.Marshal(m)
+}
+
+// Validate checks that the manifest is complete and that names are unique.
+// Values such as statuses and image names are checked when they're used.
+func (m *Manifest) Validate() error {
+	if m.APIVersion != APIVersion {
+		return fmt.Errorf("unsupported apiVersion %q, expected %q", m.APIVersion, APIVersion)
+	}
+
+	tasks := make(map[string]bool, len(m.BuildTasks))
+	for i, t := range m.BuildTasks {
+		if t.Name == "" {
+			return fmt.Errorf("buildTasks[%d]: a name is required", i)
+		}
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("build task %s: %v", t.Name, err)
+		}
+		key := strings.ToLower(t.Name)
+		if tasks[key] {
+			return fmt.Errorf("build task %s is defined more than once", t.Name)
+		}
+		tasks[key] = true
+	}
+	return nil
+}
+
+func (t *BuildTask) validate() error {
+	if !nameRegexp.MatchString(t.Name) {
+		return errors.New("the name must be 5-50 alphanumeric characters")
+	}
+	if t.Location == "" {
+		return errors.New("a location is required")
+	}
+	if t.Platform != nil && t.Platform.OS == "" {
+		return errors.New("the platform requires an os")
+	}
+	if t.Source.Type == "" {
+		return errors.New("the source requires a type")
+	}
+	if t.Source.RepositoryURL == "" {
+		return errors.New("the source requires a repositoryUrl")
+	}
+	if t.Source.TokenFile != "" && t.Source.TokenEnv != "" {
+		return errors.New("the source can't have both a tokenFile and a tokenEnv")
+	}
+	if t.Source.TokenFile == "-" {
+		return errors.New("the source's tokenFile can't be stdin")
+	}
+
+	steps := make(map[string]bool, len(t.Steps))
+	for i, s := range t.Steps {
+		if s.Name == "" {
+			return fmt.Errorf("steps[%d]: a name is required", i)
+		}
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("step %s: %v", s.Name, err)
+		}
+		key := strings.ToLower(s.Name)
+		if steps[key] {
+			return fmt.Errorf("step %s is defined more than once", s.Name)
+		}
+		steps[key] = true
+	}
+	return nil
+}
+
+func (s *Step) validate() error {
+	if !nameRegexp.MatchString(s.Name) {
+		return errors.New("the name must be 5-50 alphanumeric characters")
+	}
+
+	args := make(map[string]bool, len(s.BuildArgs))
+	for i, a := range s.BuildArgs {
+		if a.Name == "" {
+			return fmt.Errorf("buildArgs[%d]: a name is required", i)
+		}
+		if args[a.Name] {
+			return fmt.Errorf("build argument %s is defined more than once", a.Name)
+		}
+		args[a.Name] = true
+
+		sources := 0
+		for _, v := range []string{a.Value, a.ValueFile, a.ValueEnv} {
+			if v != "" {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return fmt.Errorf("build argument %s: only one of value, valueFile and valueEnv can be set", a.Name)
+		}
+		if a.ValueFile == "-" {
+			return fmt.Errorf("build argument %s: valueFile can't be stdin", a.Name)
+		}
+		if a.Secret && a.Value != "" {
+			return fmt.Errorf("build argument %s: secret values must be given with valueFile or valueEnv, not value", a.Name)
+		}
+		if a.Secret && sources == 0 {
+			return fmt.Errorf("build argument %s: secrets require a valueFile or valueEnv", a.Name)
+		}
+	}
+	return nil
+}
+
+// IsCommitTriggerEnabled reports whether commits trigger builds, which they do unless disabled.
+func (s Source) IsCommitTriggerEnabled() bool {
+	return s.CommitTrigger == nil || *s.CommitTrigger
+}
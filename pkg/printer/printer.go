@@ -0,0 +1,168 @@
+// Package printer writes objects in the output formats supported by solstice.
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	// FormatTable is a human readable table, rendered by each command.
+	FormatTable Format = "table"
+	// FormatWide is a human readable table with additional columns.
+	FormatWide Format = "wide"
+	// FormatJSON is indented JSON.
+	FormatJSON Format = "json"
+	// FormatYAML is YAML, using the same field names as JSON.
+	FormatYAML Format = "yaml"
+	// FormatJSONPath evaluates a JSONPath template, e.g. jsonpath={.id}.
+	FormatJSONPath Format = "jsonpath"
+	// FormatGoTemplate evaluates a Go template, e.g. go-template={{.id}}.
+	FormatGoTemplate Format = "go-template"
+)
+
+// Formats is the help text listing every supported output format.
+const Formats = "table|wide|json|yaml|jsonpath=<template>|go-template=<template>"
+
+// Printer writes objects in a single output format.
+//
+// Templates (jsonpath and go-template) are evaluated against the JSON form of
+// an object, so they use the same field names as -o json.
+type Printer struct {
+	format   Format
+	template string
+}
+
+// New parses an --output value such as "json" or "jsonpath={.id}" into a Printer.
+// An empty value selects the table format.
+func New(output string) (*Printer, error) {
+	if output == "" {
+		return &Printer{format: FormatTable}, nil
+	}
+
+	name, tmpl := output, ""
+	if i := strings.Index(output, "="); i >= 0 {
+		name, tmpl = output[:i], output[i+1:]
+	}
+
+	p := &Printer{format: Format(strings.ToLower(name)), template: tmpl}
+	switch p.format {
+	case FormatTable, FormatWide, FormatJSON, FormatYAML:
+		if tmpl != "" {
+			return nil, fmt.Errorf("invalid output format %q: %s doesn't take a template", output, name)
+		}
+	case FormatJSONPath, FormatGoTemplate:
+		if tmpl == "" {
+			return nil, fmt.Errorf("invalid output format %q: %s requires a template, e.g. %s=<template>", output, name, name)
+		}
+		// Parse early so that template errors are reported before any API call.
+		if _, err := p.render(nil); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid output format %q: it must be one of %s", output, Formats)
+	}
+	return p, nil
+}
+
+// Format returns the printer's output format.
+func (p *Printer) Format() Format {
+	return p.format
+}
+
+// IsHumanReadable reports whether the printer renders tables rather than structured data.
+func (p *Printer) IsHumanReadable() bool {
+	return p.format == FormatTable || p.format == FormatWide
+}
+
+// Wide reports whether tables should include additional columns.
+func (p *Printer) Wide() bool {
+	return p.format == FormatWide
+}
+
+// Print writes v to w. table is used to render the table and wide formats.
+func (p *Printer) Print(w io.Writer, v interface{}, table func(io.Writer) error) error {
+	switch p.format {
+	case FormatJSON:
+		return PrintJSON(w, v)
+	case FormatYAML:
+		return PrintYAML(w, v)
+	case FormatJSONPath, FormatGoTemplate:
+		render, err := p.render(w)
+		if err != nil {
+			return err
+		}
+		data, err := toGeneric(v)
+		if err != nil {
+			return err
+		}
+		return render(data)
+	default:
+		return table(w)
+	}
+}
+
+// render parses the printer's template and returns a function executing it against data.
+func (p *Printer) render(w io.Writer) (func(data interface{}) error, error) {
+	if p.format == FormatJSONPath {
+		jp := jsonpath.New("output").AllowMissingKeys(true)
+		if err := jp.Parse(p.template); err != nil {
+			return nil, fmt.Errorf("invalid jsonpath template %q: %v", p.template, err)
+		}
+		return func(data interface{}) error {
+			if err := jp.Execute(w, data); err != nil {
+				return err
+			}
+			_, err := fmt.Fprintln(w)
+			return err
+		}, nil
+	}
+
+	t, err := template.New("output").Parse(p.template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go-template %q: %v", p.template, err)
+	}
+	return func(data interface{}) error {
+		return t.Execute(w, data)
+	}, nil
+}
+
+// toGeneric round-trips v through JSON so templates see JSON field names.
+func toGeneric(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	err = json.Unmarshal(b, &data)
+	return data, err
+}
+
+// PrintJSON writes v to w as indented JSON.
+func PrintJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// PrintYAML writes v to w as YAML.
+func PrintYAML(w io.Writer, v interface{}) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,36 @@
+package view
+
+import "time"
+
+// Subscription is the representation of a subscription an account can access.
+type Subscription struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	State     string `json:"state,omitempty"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+// Account is the representation of an account signed in with 'solstice login'.
+type Account struct {
+	Name string `json:"name"`
+	// Type is either "user" or "servicePrincipal".
+	Type          string         `json:"type"`
+	Cloud         string         `json:"cloud"`
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// Identity is the representation of the identity solstice authenticates as.
+// It never includes the token itself.
+type Identity struct {
+	Cloud string `json:"cloud"`
+	// Subscription is omitted when no subscription could be selected.
+	Subscription *Subscription `json:"subscription,omitempty"`
+	GrantType    string        `json:"grantType"`
+	Source       string        `json:"source"`
+	User         string        `json:"user,omitempty"`
+	AppID        string        `json:"appId,omitempty"`
+	ObjectID     string        `json:"objectId,omitempty"`
+	TenantID     string        `json:"tenantId"`
+	Audience     string        `json:"audience"`
+	ExpiresOn    *time.Time    `json:"expiresOn,omitempty"`
+}
@@ -0,0 +1,158 @@
+// Package view defines the stable, serializable representations of the
+// resources solstice prints. Unlike the SDK models they never change shape
+// when the service API version changes, so scripts can rely on them.
+package view
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+	"github.com/Azure/go-autorest/autorest/date"
+)
+
+// Build is the representation of a build.
+type Build struct {
+	ID                 string     `json:"id"`
+	Status             string     `json:"status"`
+	BuildType          string     `json:"buildType,omitempty"`
+	Trigger            string     `json:"trigger,omitempty"`
+	BuildTask          string     `json:"buildTask,omitempty"`
+	Platform           *Platform  `json:"platform,omitempty"`
+	CreateTime         *time.Time `json:"createTime,omitempty"`
+	StartTime          *time.Time `json:"startTime,omitempty"`
+	FinishTime         *time.Time `json:"finishTime,omitempty"`
+	QueueWaitSeconds   *int64     `json:"queueWaitSeconds,omitempty"`
+	RunDurationSeconds *int64     `json:"runDurationSeconds,omitempty"`
+	IsArchiveEnabled   bool       `json:"isArchiveEnabled"`
+	OutputImages       []Image    `json:"outputImages"`
+}
+
+// BuildList is the representation of a list of builds.
+type BuildList struct {
+	Items []Build `json:"items"`
+}
+
+// Platform is the representation of the platform properties of a build.
+type Platform struct {
+	OsType string `json:"osType,omitempty"`
+	CPU    *int32 `json:"cpu,omitempty"`
+}
+
+// Image is the representation of an image produced by a build.
+type Image struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+}
+
+// NewBuild converts an SDK build into its view. Missing optional fields are left empty.
+func NewBuild(b containerregistry.Build) Build {
+	v := Build{
+		OutputImages: []Image{},
+	}
+	if b.BuildProperties == nil {
+		v.ID = str(b.Name)
+		return v
+	}
+
+	p := b.BuildProperties
+	v.ID = str(p.BuildID)
+	if v.ID == "" {
+		v.ID = str(b.Name)
+	}
+	v.Status = string(p.Status)
+	v.BuildType = string(p.BuildType)
+	v.Trigger = str(p.Trigger)
+	v.BuildTask = str(p.BuildTask)
+	v.Platform = NewPlatform(p.Platform)
+	v.CreateTime = toTime(p.CreateTime)
+	v.StartTime = toTime(p.StartTime)
+	v.FinishTime = toTime(p.FinishTime)
+	v.QueueWaitSeconds = seconds(v.CreateTime, v.StartTime)
+	v.RunDurationSeconds = seconds(v.StartTime, v.FinishTime)
+	v.IsArchiveEnabled = p.IsArchiveEnabled != nil && *p.IsArchiveEnabled
+
+	if p.OutputImages != nil {
+		for _, img := range *p.OutputImages {
+			v.OutputImages = append(v.OutputImages, Image{
+				Repository: str(img.RepositoryName),
+				Tag:        str(img.Tag),
+				Digest:     str(img.Digest),
+			})
+		}
+	}
+	return v
+}
+
+// NewBuildList converts SDK builds into a list view.
+func NewBuildList(builds []containerregistry.Build) BuildList {
+	l := BuildList{Items: make([]Build, len(builds))}
+	for i, b := range builds {
+		l.Items[i] = NewBuild(b)
+	}
+	return l
+}
+
+// NewPlatform converts SDK platform properties into their view, or nil if p is nil.
+func NewPlatform(p *containerregistry.PlatformProperties) *Platform {
+	if p == nil {
+		return nil
+	}
+	return &Platform{
+		OsType: string(p.OsType),
+		CPU:    p.CPU,
+	}
+}
+
+// QueueWait returns how long the build waited before starting, if known.
+func (b Build) QueueWait() (time.Duration, bool) {
+	return duration(b.QueueWaitSeconds)
+}
+
+// RunDuration returns how long the build ran, if known.
+func (b Build) RunDuration() (time.Duration, bool) {
+	return duration(b.RunDurationSeconds)
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func toTime(t *date.Time) *time.Time {
+	if t == nil || t.Time.IsZero() {
+		return nil
+	}
+	tt := t.Time
+	return &tt
+}
+
+func seconds(from, to *time.Time) *int64 {
+	if from == nil || to == nil {
+		return nil
+	}
+	s := int64(to.Sub(*from) / time.Second)
+	return &s
+}
+
+func duration(s *int64) (time.Duration, bool) {
+	if s == nil {
+		return 0, false
+	}
+	return time.Duration(*s) * time.Second, true
+}
+
+// Cancellation is the representation of the outcome of canceling a build.
+type Cancellation struct {
+	BuildID string `json:"buildId"`
+	// Result is one of "Requested", "Canceled" or "Failed".
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CancellationList is the representation of the outcomes of canceling builds.
+type CancellationList struct {
+	Items []Cancellation `json:"items"`
+}
@@ -0,0 +1,121 @@
+package view
+
+import (
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+)
+
+// SecretMask replaces the value of secret build arguments.
+const SecretMask = "*****"
+
+// BuildStep is the representation of a build step. Only Docker build steps
+// carry properties beyond the name, type and provisioning state.
+type BuildStep struct {
+	Name                  string                `json:"name"`
+	Type                  string                `json:"type,omitempty"`
+	ProvisioningState     string                `json:"provisioningState,omitempty"`
+	Branch                string                `json:"branch,omitempty"`
+	ImageName             string                `json:"imageName,omitempty"`
+	IsPushEnabled         bool                  `json:"isPushEnabled"`
+	DockerFilePath        string                `json:"dockerFilePath,omitempty"`
+	ContextPath           string                `json:"contextPath,omitempty"`
+	BuildArguments        []BuildArgument       `json:"buildArguments"`
+	BaseImageTrigger      string                `json:"baseImageTrigger,omitempty"`
+	BaseImageDependencies []BaseImageDependency `json:"baseImageDependencies"`
+}
+
+// BuildStepList is the representation of a list of build steps.
+type BuildStepList struct {
+	Items []BuildStep `json:"items"`
+}
+
+// BuildArgument is the representation of a build argument. Secret values are always masked.
+type BuildArgument struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	IsSecret bool   `json:"isSecret"`
+}
+
+// BuildArgumentList is the representation of a list of build arguments.
+type BuildArgumentList struct {
+	Items []BuildArgument `json:"items"`
+}
+
+// BaseImageDependency is the representation of an image a build step depends on.
+type BaseImageDependency struct {
+	Type                 string `json:"type,omitempty"`
+	Repository           string `json:"repository,omitempty"`
+	Tag                  string `json:"tag,omitempty"`
+	Digest               string `json:"digest,omitempty"`
+	IsAutoTriggerEnabled bool   `json:"isAutoTriggerEnabled"`
+}
+
+// NewBuildStep converts an SDK build step into its view.
+func NewBuildStep(s containerregistry.BuildStep) BuildStep {
+	v := BuildStep{
+		Name:                  str(s.Name),
+		BuildArguments:        []BuildArgument{},
+		BaseImageDependencies: []BaseImageDependency{},
+	}
+	if s.BasicBuildStepProperties == nil {
+		return v
+	}
+
+	d, ok := s.BasicBuildStepProperties.AsDockerBuildStep()
+	if !ok {
+		if p, ok := s.BasicBuildStepProperties.AsBuildStepProperties(); ok {
+			v.Type = string(p.Type)
+			v.ProvisioningState = string(p.ProvisioningState)
+		}
+		return v
+	}
+
+	v.Type = string(containerregistry.TypeDocker)
+	v.ProvisioningState = string(d.ProvisioningState)
+	v.Branch = str(d.Branch)
+	v.ImageName = str(d.ImageName)
+	v.IsPushEnabled = d.IsPushEnabled != nil && *d.IsPushEnabled
+	v.DockerFilePath = str(d.DockerFilePath)
+	v.ContextPath = str(d.ContextPath)
+	v.BaseImageTrigger = string(d.BaseImageTrigger)
+	if d.BuildArguments != nil {
+		v.BuildArguments = NewBuildArguments(*d.BuildArguments)
+	}
+	if d.BaseImageDependencies != nil {
+		for _, dep := range *d.BaseImageDependencies {
+			v.BaseImageDependencies = append(v.BaseImageDependencies, BaseImageDependency{
+				Type:                 string(dep.Type),
+				Repository:           str(dep.RepositoryName),
+				Tag:                  str(dep.Tag),
+				Digest:               str(dep.Digest),
+				IsAutoTriggerEnabled: dep.IsAutoTriggerEnabled != nil && *dep.IsAutoTriggerEnabled,
+			})
+		}
+	}
+	return v
+}
+
+// NewBuildStepList converts SDK build steps into a list view.
+func NewBuildStepList(steps []containerregistry.BuildStep) BuildStepList {
+	l := BuildStepList{Items: make([]BuildStep, len(steps))}
+	for i, s := range steps {
+		l.Items[i] = NewBuildStep(s)
+	}
+	return l
+}
+
+// NewBuildArguments converts SDK build arguments into their views, masking secret values.
+func NewBuildArguments(args []containerregistry.BuildArgument) []BuildArgument {
+	result := make([]BuildArgument, 0, len(args))
+	for _, a := range args {
+		arg := BuildArgument{
+			Name:     str(a.Name),
+			Value:    str(a.Value),
+			IsSecret: a.IsSecret != nil && *a.IsSecret,
+		}
+		if arg.IsSecret {
+			arg.Value = SecretMask
+		}
+		result = append(result, arg)
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package view
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2018-02-01-preview/containerregistry"
+)
+
+// BuildTask is the representation of a build task. Source control credentials are never included.
+type BuildTask struct {
+	Name              string            `json:"name"`
+	Alias             string            `json:"alias,omitempty"`
+	Status            string            `json:"status,omitempty"`
+	ProvisioningState string            `json:"provisioningState,omitempty"`
+	CreationTime      *time.Time        `json:"creationTime,omitempty"`
+	SourceRepository  *SourceRepository `json:"sourceRepository,omitempty"`
+	Platform          *Platform         `json:"platform,omitempty"`
+	TimeoutSeconds    *int32            `json:"timeoutSeconds,omitempty"`
+	Location          string            `json:"location,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+}
+
+// BuildTaskList is the representation of a list of build tasks.
+type BuildTaskList struct {
+	Items []BuildTask `json:"items"`
+}
+
+// SourceRepository is the representation of a build task's source repository.
+type SourceRepository struct {
+	SourceControlType      string `json:"sourceControlType,omitempty"`
+	RepositoryURL          string `json:"repositoryUrl,omitempty"`
+	IsCommitTriggerEnabled bool   `json:"isCommitTriggerEnabled"`
+}
+
+// NewBuildTask converts an SDK build task into its view.
+func NewBuildTask(t containerregistry.BuildTask) BuildTask {
+	v := BuildTask{
+		Name:     str(t.Name),
+		Location: str(t.Location),
+	}
+	if len(t.Tags) > 0 {
+		v.Tags = make(map[string]string, len(t.Tags))
+		for k, val := range t.Tags {
+			v.Tags[k] = str(val)
+		}
+	}
+
+	p := t.BuildTaskProperties
+	if p == nil {
+		return v
+	}
+	v.Alias = str(p.Alias)
+	v.Status = string(p.Status)
+	v.ProvisioningState = string(p.ProvisioningState)
+	v.CreationTime = toTime(p.CreationDate)
+	v.Platform = NewPlatform(p.Platform)
+	v.TimeoutSeconds = p.Timeout
+	if r := p.SourceRepository; r != nil {
+		v.SourceRepository = &SourceRepository{
+			SourceControlType:      string(r.SourceControlType),
+			RepositoryURL:          str(r.RepositoryURL),
+			IsCommitTriggerEnabled: r.IsCommitTriggerEnabled != nil && *r.IsCommitTriggerEnabled,
+		}
+	}
+	return v
+}
+
+// NewBuildTaskList converts SDK build tasks into a list view.
+func NewBuildTaskList(tasks []containerregistry.BuildTask) BuildTaskList {
+	l := BuildTaskList{Items: make([]BuildTask, len(tasks))}
+	for i, t := range tasks {
+		l.Items[i] = NewBuildTask(t)
+	}
+	return l
+}
+
+// TaskSource is the representation of a build task's source repository and
+// its source control credentials. The tokens themselves are never included.
+type TaskSource struct {
+	BuildTask              string `json:"buildTask"`
+	SourceControlType      string `json:"sourceControlType,omitempty"`
+	RepositoryURL          string `json:"repositoryUrl,omitempty"`
+	IsCommitTriggerEnabled bool   `json:"isCommitTriggerEnabled"`
+	TokenType              string `json:"tokenType,omitempty"`
+	Scope                  string `json:"scope,omitempty"`
+	// TokenLifetimeSeconds is how long the token is valid for once issued, if known.
+	TokenLifetimeSeconds *int32 `json:"tokenLifetimeSeconds,omitempty"`
+	// TokenRotatedAt is when the token was rotated with solstice, if known.
+	TokenRotatedAt *time.Time `json:"tokenRotatedAt,omitempty"`
+	// TokenExpiresAt is when the token expires, if both its lifetime and rotation time are known.
+	TokenExpiresAt  *time.Time `json:"tokenExpiresAt,omitempty"`
+	HasRefreshToken bool       `json:"hasRefreshToken"`
+}
+
+// TaskSourceList is the representation of the source repositories of build tasks.
+type TaskSourceList struct {
+	Items []TaskSource `json:"items"`
+}
+
+// NewTaskSource converts the SDK source repository properties of a build task
+// into its view. rotatedAt is when the token was rotated, if known.
+func NewTaskSource(task string, r containerregistry.SourceRepositoryProperties, rotatedAt *time.Time) TaskSource {
+	v := TaskSource{
+		BuildTask:              task,
+		SourceControlType:      string(r.SourceControlType),
+		RepositoryURL:          str(r.RepositoryURL),
+		IsCommitTriggerEnabled: r.IsCommitTriggerEnabled != nil && *r.IsCommitTriggerEnabled,
+	}
+	if a := r.SourceControlAuthProperties; a != nil {
+		v.TokenType = string(a.TokenType)
+		v.Scope = str(a.Scope)
+		v.TokenLifetimeSeconds = a.ExpiresIn
+		v.TokenRotatedAt = rotatedAt
+		v.HasRefreshToken = a.RefreshToken != nil && *a.RefreshToken != ""
+	}
+	if lifetime, ok := v.Lifetime(); ok && v.TokenRotatedAt != nil {
+		expiresAt := v.TokenRotatedAt.Add(lifetime)
+		v.TokenExpiresAt = &expiresAt
+	}
+	return v
+}
+
+// Lifetime returns how long the token is valid for once issued, if known.
+func (s TaskSource) Lifetime() (time.Duration, bool) {
+	if s.TokenLifetimeSeconds == nil {
+		return 0, false
+	}
+	return time.Duration(*s.TokenLifetimeSeconds) * time.Second, true
+}
+
+// ExpiresIn returns how long the token remains valid at now, if known. It's
+// negative once the token has expired.
+func (s TaskSource) ExpiresIn(now time.Time) (time.Duration, bool) {
+	if s.TokenExpiresAt == nil {
+		return 0, false
+	}
+	return s.TokenExpiresAt.Sub(now), true
+}
+
+// TokenRotation is the representation of the outcome of rotating a build task's source control token.
+type TokenRotation struct {
+	BuildTask string `json:"buildTask"`
+	// Result is either "Rotated" or "Failed".
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TokenRotationList is the representation of the outcomes of rotating source control tokens.
+type TokenRotationList struct {
+	Items []TokenRotation `json:"items"`
+}
@@ -0,0 +1,17 @@
+package view
+
+// PlanAction is the representation of a single change planned by 'solstice apply'.
+type PlanAction struct {
+	// Action is one of "create", "update", "replace" or "delete".
+	Action string `json:"action"`
+	// Kind is either "BuildTask" or "BuildStep".
+	Kind      string   `json:"kind"`
+	BuildTask string   `json:"buildTask"`
+	Step      string   `json:"step,omitempty"`
+	Changes   []string `json:"changes,omitempty"`
+}
+
+// Plan is the representation of the changes needed to converge a registry to a manifest.
+type Plan struct {
+	Items []PlanAction `json:"items"`
+}